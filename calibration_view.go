@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"time"
+
+	"drazil/tournament/engine"
+	"drazil/tournament/soundbank"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// audioOffset is the latency of the sound output against the display. The
+// engines trigger the signals that much earlier, or later for a negative
+// offset, so that sound and light change at the same moment.
+var audioOffset time.Duration
+
+const maxAudioOffset = 500 * time.Millisecond
+
+func setAudioOffset(offset time.Duration) {
+	audioOffset = offset
+	timer.SetSignalOffset(offset)
+	match.SetSignalOffset(offset)
+	if finals.shootOff != nil {
+		finals.shootOff.SetSignalOffset(offset)
+	}
+}
+
+// idle reports whether neither an end nor a match is running, so that
+// the calibration clicks cannot mix with signals.
+func idle() bool {
+	state := timer.State()
+	if state.Stage != engine.Halt && state.Stage != engine.Finished {
+		return false
+	}
+	return !match.State().Running && (finals.shootOff == nil || !finals.shootOff.State().Running)
+}
+
+// calibrationView plays a click every second and flashes a marker at the
+// same scheduled instant, both shifted by the offset being edited. The
+// operator changes the offset until click and flash coincide.
+type calibrationView struct {
+	offset time.Duration
+	start  time.Time
+	beat   time.Duration
+	player *audio.Player
+}
+
+var calibration calibrationView
+
+const beat = time.Second
+
+func (c *calibrationView) open() {
+	if c.player == nil {
+		stream, err := soundbank.Tone{Frequency: 2000, Attack: 1, Release: 5, Duration: 30}.Synthesize(audioContext.SampleRate())
+		if err == nil {
+			c.player, err = audioContext.NewPlayer(stream)
+		}
+		if err != nil {
+			log.Printf("calibration: %v", err)
+		}
+	}
+	if c.player != nil {
+		c.player.SetVolume(volumes.gain())
+	}
+	c.offset = audioOffset
+	c.start = time.Now()
+	c.beat = -1
+	view = CalibrationView
+}
+
+func (c *calibrationView) update() {
+	step := 10 * time.Millisecond
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		step = time.Millisecond
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+		c.offset = clampDuration(c.offset-step, -maxAudioOffset, maxAudioOffset)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
+		c.offset = clampDuration(c.offset+step, -maxAudioOffset, maxAudioOffset)
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEnter) {
+		setAudioOffset(c.offset)
+		settingsFile.AudioOffset = int(c.offset / time.Millisecond)
+		if err := settingsFile.Save(configFile); err != nil {
+			log.Printf("saving audio offset: %v", err)
+		}
+		view = MainView
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEscape) {
+		view = MainView
+	}
+
+	elapsed := time.Since(c.start) - c.clickDelay()
+	if n := elapsed / beat; elapsed >= 0 && n != c.beat {
+		c.beat = n
+		if c.player != nil {
+			c.player.Rewind()
+			c.player.Play()
+		}
+	}
+}
+
+func (c *calibrationView) clickDelay() time.Duration {
+	if c.offset < 0 {
+		return -c.offset
+	}
+	return 0
+}
+
+func (c *calibrationView) flashDelay() time.Duration {
+	if c.offset > 0 {
+		return c.offset
+	}
+	return 0
+}
+
+func (c *calibrationView) draw(screen *ebiten.Image) {
+	text.Draw(screen, "Latenz-Kalibrierung", infoFontLarge, 200, 50, colorWhite)
+	text.Draw(screen, "[PFEILE] Versatz +/-10 ms ([SHIFT] 1 ms)  [RETURN] Speichern  [ESC] Abbrechen", infoFontSmall, 200, 80, colorWhite)
+	text.Draw(screen, "Versatz so einstellen, dass Klick und Blitz zusammenfallen.", infoFontSmall, 200, 110, colorWhite)
+	elapsed := time.Since(c.start) - c.flashDelay()
+	marker := colorDarkGray
+	if elapsed >= 0 && elapsed%beat < 100*time.Millisecond {
+		marker = colorWhite
+	}
+	screen.SubImage(image.Rect(362, 200, 662, 500)).(*ebiten.Image).Fill(marker)
+	text.Draw(screen, fmt.Sprintf("Audio-Versatz: %+d ms", c.offset/time.Millisecond), infoFontLarge, 362, 580, colorYellow)
+}
+
+func clampDuration(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"drazil/tournament/engine"
+	"drazil/tournament/profiles"
+	"drazil/tournament/remote"
+	"drazil/tournament/soundbank"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+type setting struct {
+	label  string
+	format func(s *settingsEditor) string
+	change func(s *settingsEditor, delta int)
+	// preview is the state shown in the tournament preview while the
+	// setting is selected
+	preview func(s *settingsEditor) engine.State
+}
+
+// settingsEditor holds a working copy of the settings. Nothing is applied
+// before the copy has been validated. base is the configuration the copy
+// started from; only values that differ from it are saved, so timings
+// overridden on the command line stay out of the profile.
+type settingsEditor struct {
+	selected   int
+	profile    int
+	config     engine.Config
+	base       engine.Config
+	rotation   string
+	fullscreen bool
+	volume     int
+	levels     map[string]int
+	role       int
+	message    string
+	saved      bool
+	preview    *ebiten.Image
+}
+
+var (
+	configuration settingsEditor
+	settings      = []setting{
+		{
+			label:  "Profil",
+			format: func(s *settingsEditor) string { return settingsFile.Profiles[s.profile].Name },
+			change: func(s *settingsEditor, delta int) {
+				n := len(settingsFile.Profiles)
+				s.profile = (s.profile + n + delta) % n
+				s.config = settingsFile.Profiles[s.profile].Config()
+				s.base = s.config
+				s.rotation = settingsFile.Profiles[s.profile].Rotation
+			},
+		},
+		{
+			label: "Rotation",
+			format: func(s *settingsEditor) string {
+				return strings.Join(s.config.Rotation[0], " / ")
+			},
+			change: func(s *settingsEditor, delta int) {
+				profile := settingsFile.Profiles[s.profile]
+				n := len(profiles.Rotations)
+				i := indexOf(profiles.Rotations, s.rotation)
+				for {
+					i = (i + n + delta) % n
+					if profiles.Rotations[i] != profiles.RotationCustom || len(profile.Details) > 0 {
+						break
+					}
+				}
+				s.rotation = profiles.Rotations[i]
+				s.config.Rotation = profile.RotationDetails(s.rotation)
+			},
+			preview: func(s *settingsEditor) engine.State {
+				return engine.State{Light: engine.Red, Pair: s.config.Rotation[0][0]}
+			},
+		},
+		{
+			label:  "Schiesszeit",
+			format: func(s *settingsEditor) string { return fmt.Sprintf("%d s", s.config.ActionDuration) },
+			change: func(s *settingsEditor, delta int) {
+				s.config.ActionDuration = clamp(s.config.ActionDuration+delta*10, 10, 600)
+			},
+			preview: func(s *settingsEditor) engine.State {
+				return engine.State{Stage: engine.Action, Light: engine.Green, Duration: s.config.ActionDuration}
+			},
+		},
+		{
+			label:  "Warnzeit",
+			format: func(s *settingsEditor) string { return fmt.Sprintf("%d s", s.config.WarnDuration) },
+			change: func(s *settingsEditor, delta int) {
+				s.config.WarnDuration = clamp(s.config.WarnDuration+delta*5, 0, 600)
+			},
+			preview: func(s *settingsEditor) engine.State {
+				return engine.State{Stage: engine.Action, Light: engine.Yellow, Duration: s.config.WarnDuration}
+			},
+		},
+		{
+			label:  "Vorbereitung 1",
+			format: func(s *settingsEditor) string { return fmt.Sprintf("%d s", s.config.PrepareDuration[0]) },
+			change: func(s *settingsEditor, delta int) {
+				s.config.PrepareDuration[0] = clamp(s.config.PrepareDuration[0]+delta, 0, 60)
+			},
+			preview: func(s *settingsEditor) engine.State {
+				return engine.State{Stage: engine.Prepare, Light: engine.Red, Duration: s.config.PrepareDuration[0]}
+			},
+		},
+		{
+			label:  "Vorbereitung 2",
+			format: func(s *settingsEditor) string { return fmt.Sprintf("%d s", s.config.PrepareDuration[1]) },
+			change: func(s *settingsEditor, delta int) {
+				s.config.PrepareDuration[1] = clamp(s.config.PrepareDuration[1]+delta, 0, 60)
+			},
+			preview: func(s *settingsEditor) engine.State {
+				return engine.State{Stage: engine.Prepare, Light: engine.Red, Duration: s.config.PrepareDuration[1], Half: 1}
+			},
+		},
+		{
+			label:  "Lautstaerke",
+			format: func(s *settingsEditor) string { return fmt.Sprintf("%d %%", s.volume) },
+			change: func(s *settingsEditor, delta int) {
+				s.volume = clamp(s.volume+delta*5, 0, maxVolume)
+			},
+		},
+		{
+			label:  "Signal",
+			format: func(s *settingsEditor) string { return roleLabels[soundbank.Roles[s.role]] },
+			change: func(s *settingsEditor, delta int) {
+				n := len(soundbank.Roles)
+				s.role = (s.role + n + delta) % n
+			},
+		},
+		{
+			label:  "Signalpegel",
+			format: func(s *settingsEditor) string { return fmt.Sprintf("%d %%", s.levels[soundbank.Roles[s.role]]) },
+			change: func(s *settingsEditor, delta int) {
+				role := soundbank.Roles[s.role]
+				s.levels[role] = clamp(s.levels[role]+delta*10, 0, maxLevel)
+			},
+		},
+		{
+			label: "Vollbild",
+			format: func(s *settingsEditor) string {
+				if s.fullscreen {
+					return "an"
+				}
+				return "aus"
+			},
+			change: func(s *settingsEditor, delta int) {
+				s.fullscreen = !s.fullscreen
+			},
+		},
+	}
+)
+
+func (s *settingsEditor) open() {
+	s.profile = settingsFile.Index(settingsFile.Current().Name)
+	s.config = timer.Config()
+	s.base = s.config
+	s.rotation = settingsFile.Profiles[s.profile].Rotation
+	s.fullscreen = fullscreen
+	v := volumes.Volume()
+	s.volume = v.Master
+	s.levels = v.Signals
+	s.message = ""
+	s.saved = false
+}
+
+// update handles the keys of the configuration view and reports whether a
+// key was consumed.
+func (s *settingsEditor) update() bool {
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) {
+		s.selected = (s.selected + len(settings) - 1) % len(settings)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		s.selected = (s.selected + 1) % len(settings)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+		settings[s.selected].change(s, -1)
+		s.message = ""
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
+		settings[s.selected].change(s, 1)
+		s.message = ""
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEnter) {
+		s.saved = false
+		if err := s.validate(); err != nil {
+			s.message = err.Error()
+		} else if err := s.apply(); err != nil {
+			s.message = fmt.Sprintf("Speichern fehlgeschlagen: %v", err)
+		} else {
+			s.message = "Einstellungen gespeichert"
+			s.saved = true
+		}
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEscape) {
+		view = MainView
+	} else {
+		return false
+	}
+	return true
+}
+
+func (s *settingsEditor) validate() error {
+	if s.config.WarnDuration >= s.config.ActionDuration {
+		return errors.New("Warnzeit muss kleiner als die Schiesszeit sein")
+	}
+	return nil
+}
+
+// apply activates the edited settings and writes them back to the
+// configuration file under the selected profile.
+func (s *settingsEditor) apply() error {
+	profile := &settingsFile.Profiles[s.profile]
+	if settingsFile.Current().Name != profile.Name {
+		if err := timer.SetSchedule(profile.Schedule); err != nil {
+			return err
+		}
+	}
+	config = s.config
+	timer.SetConfig(config)
+	if err := volumes.SetVolume(remote.Volume{Master: s.volume, Signals: s.levels}); err != nil {
+		return err
+	}
+	if fullscreen != s.fullscreen {
+		fullscreen = s.fullscreen
+		ebiten.SetFullscreen(fullscreen)
+	}
+	profile.SetConfig(s.edited(profile.Config()))
+	profile.Rotation = s.rotation
+	settingsFile.Active = profile.Name
+	settingsFile.Fullscreen = s.fullscreen
+	if err := settingsFile.Save(configFile); err != nil {
+		return err
+	}
+	s.base = s.config
+	return nil
+}
+
+// edited returns saved with the timings changed in the editor.
+func (s *settingsEditor) edited(saved engine.Config) engine.Config {
+	if s.config.ActionDuration != s.base.ActionDuration {
+		saved.ActionDuration = s.config.ActionDuration
+	}
+	if s.config.WarnDuration != s.base.WarnDuration {
+		saved.WarnDuration = s.config.WarnDuration
+	}
+	for i := range saved.PrepareDuration {
+		if s.config.PrepareDuration[i] != s.base.PrepareDuration[i] {
+			saved.PrepareDuration[i] = s.config.PrepareDuration[i]
+		}
+	}
+	return saved
+}
+
+func (s *settingsEditor) draw(screen *ebiten.Image) {
+	text.Draw(screen, "Konfiguration", infoFontLarge, 50, 50, colorWhite)
+	text.Draw(screen, "[PFEILE] Auswahl/Wert  [RETURN] Uebernehmen  [ESC] Zurueck", infoFontSmall, 50, 80, colorWhite)
+	for i, item := range settings {
+		c := colorWhite
+		if i == s.selected {
+			c = colorYellow
+		}
+		y := 150 + i*45
+		text.Draw(screen, item.label, infoFontLarge, 50, y, c)
+		text.Draw(screen, item.format(s), infoFontLarge, 330, y, c)
+	}
+	if s.message != "" {
+		c := colorRed
+		if s.saved {
+			c = colorGreen
+		}
+		text.Draw(screen, s.message, infoFontSmall, 50, 720, c)
+	}
+
+	if s.preview == nil {
+		s.preview = ebiten.NewImage(screenWidth, screenHeight)
+	}
+	state := engine.State{Light: engine.Red, Pair: timer.State().Pair}
+	if p := settings[s.selected].preview; p != nil {
+		state = p(s)
+		if state.Pair == "" {
+			state.Pair = timer.State().Pair
+		}
+	}
+	s.preview.Fill(colorBlack)
+	drawTournament(s.preview, state)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(0.5, 0.5)
+	op.GeoM.Translate(480, 340)
+	screen.DrawImage(s.preview, op)
+}
+
+func indexOf(values []string, v string) int {
+	for i, value := range values {
+		if value == v {
+			return i
+		}
+	}
+	return 0
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,73 @@
+package control
+
+import (
+	"errors"
+	"time"
+)
+
+// Command is an operator action that can be triggered from the keyboard or
+// from a remote source.
+type Command string
+
+const (
+	Start   Command = "start"
+	Cancel  Command = "cancel"
+	Pause   Command = "pause"
+	Resume  Command = "resume"
+	Restart Command = "restart"
+	// Emergency is the emergency button of a keypad. It only pauses; a
+	// second press must not resume shooting while people may still be on
+	// the range, so resuming stays an explicit Resume.
+	Emergency Command = "emergency"
+)
+
+var (
+	// ErrIgnored is returned when a command does not apply to the current
+	// state, e.g. start while an end is running.
+	ErrIgnored = errors.New("command ignored in current state")
+	ErrUnknown = errors.New("unknown command")
+	ErrTimeout = errors.New("timer did not respond")
+)
+
+type request struct {
+	command Command
+	done    chan error
+}
+
+// Queue hands commands from other goroutines to the game loop, which
+// executes them in Drain.
+type Queue struct {
+	requests chan request
+}
+
+func NewQueue() *Queue {
+	return &Queue{requests: make(chan request, 16)}
+}
+
+// Send queues a command and waits until it has been executed.
+func (q *Queue) Send(command Command) error {
+	r := request{command: command, done: make(chan error, 1)}
+	select {
+	case q.requests <- r:
+	case <-time.After(time.Second):
+		return ErrTimeout
+	}
+	select {
+	case err := <-r.done:
+		return err
+	case <-time.After(2 * time.Second):
+		return ErrTimeout
+	}
+}
+
+// Drain executes all queued commands without blocking.
+func (q *Queue) Drain(execute func(Command) error) {
+	for {
+		select {
+		case r := <-q.requests:
+			r.done <- execute(r.command)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package engine
+
+import "time"
+
+// Clock is the time source of an Engine. It is injected so the end timing
+// can be driven by a fake clock instead of the wall clock.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock returns a Clock backed by time.Now.
+func SystemClock() Clock {
+	return systemClock{}
+}
@@ -0,0 +1,709 @@
+package engine
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type Stage int
+
+const (
+	Halt Stage = iota
+	Prepare
+	Action
+	Break
+	Finished
+)
+
+func (s Stage) String() string {
+	switch s {
+	case Prepare:
+		return "prepare"
+	case Action:
+		return "action"
+	case Break:
+		return "break"
+	case Finished:
+		return "finished"
+	}
+	return "halt"
+}
+
+// Running reports whether an end is being shot in the stage.
+func (s Stage) Running() bool {
+	return s == Prepare || s == Action
+}
+
+type Light int
+
+const (
+	Red Light = iota
+	Yellow
+	Green
+	Off
+)
+
+func (l Light) String() string {
+	switch l {
+	case Yellow:
+		return "yellow"
+	case Green:
+		return "green"
+	case Off:
+		return "off"
+	}
+	return "red"
+}
+
+// Rotation lists the ends of a rotation cycle, each with the labels of its
+// details in shooting order.
+type Rotation [][]string
+
+var (
+	SingleDetail = Rotation{{"A-D"}}
+	TwoDetails   = Rotation{{"A-B", "C-D"}, {"C-D", "A-B"}}
+	ThreeDetails = Rotation{{"A-B", "C-D", "E-F"}}
+)
+
+// Config holds the timing of an end. PrepareDuration[0] applies to the
+// first detail of an end, PrepareDuration[1] to every following one.
+type Config struct {
+	ActionDuration  int
+	WarnDuration    int
+	PrepareDuration [2]int
+	Rotation        Rotation
+}
+
+func DefaultConfig() Config {
+	return Config{
+		ActionDuration:  120,
+		WarnDuration:    30,
+		PrepareDuration: [2]int{10, 20},
+		Rotation:        TwoDetails,
+	}
+}
+
+// State is a snapshot of the engine. Duration is the number of seconds
+// left in the current stage, Round counts the details shot in the current
+// rotation cycle and Half is the position of the detail within its end.
+// With a schedule, End is the current or next end out of Ends ends of the
+// distance, counting practice ends separately. Paused freezes the countdown
+// of the current stage. MakeUp marks an ad-hoc end for the listed Targets
+// and ShootOff a single arrow shoot-off; neither counts in the rotation or
+// schedule.
+type State struct {
+	Stage    Stage
+	Paused   bool
+	Light    Light
+	Duration int
+	Round    int
+	Half     int
+	Pair     string
+	Session  string
+	Distance string
+	Practice bool
+	End      int
+	Ends     int
+	MakeUp   bool
+	ShootOff bool
+	Targets  string
+}
+
+// Engine runs the timing of an end: the preparation phase, the shooting
+// phase with its warning period and the rotation of rounds and halves.
+// All methods are safe for concurrent use.
+type Engine struct {
+	mu       sync.Mutex
+	config   Config
+	clock    Clock
+	state    State
+	end      int
+	schedule Schedule
+	position position
+	deadline time.Time
+	left     time.Duration
+	action   int
+	warn     int
+	shift    signalShift
+	events   []Event
+	// interrupted is the stage a make-up end or shoot-off was started from
+	// and resumed is its deadline; both are restored when the extra end
+	// finishes.
+	interrupted Stage
+	resumed     time.Time
+	// signals counts the signals triggered and last is the latest of them,
+	// before they are shifted, so that followers can shift them by their
+	// own offset.
+	signals   uint64
+	last      Signal
+	following bool
+}
+
+func New(config Config, clock Clock) *Engine {
+	if clock == nil {
+		clock = SystemClock()
+	}
+	if len(config.Rotation) == 0 {
+		config.Rotation = TwoDetails
+	}
+	e := &Engine{config: config, clock: clock}
+	e.state = State{Stage: Halt, Light: Red}
+	e.state.Pair = e.pair()
+	return e
+}
+
+func (e *Engine) Config() Config {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.config
+}
+
+// SetConfig replaces the timing configuration. A running end keeps its
+// deadline; the new values apply from the next stage on. If the rotation
+// no longer contains the current position the rotation starts over.
+func (e *Engine) SetConfig(config Config) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(config.Rotation) == 0 {
+		config.Rotation = TwoDetails
+	}
+	e.config = config
+	if e.end >= len(config.Rotation) || e.state.Half >= len(config.Rotation[e.end]) {
+		e.end = 0
+		e.state.Round = 0
+		e.state.Half = 0
+	}
+	e.state.Pair = e.pair()
+}
+
+// SetSchedule replaces the schedule and moves to its first end. An invalid
+// schedule is rejected and the current one kept.
+func (e *Engine) SetSchedule(schedule Schedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.schedule = schedule
+	e.position = position{}
+	e.updateSchedule()
+	return nil
+}
+
+// SetSignalOffset shifts the signals against the transitions that trigger
+// them by the latency of the sound output. A positive offset triggers the
+// signals at the end of a stage that much earlier, so they are heard at
+// the moment the light changes; a negative offset delays all signals.
+// Signals caused by commands cannot be anticipated and follow at once.
+func (e *Engine) SetSignalOffset(offset time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shift.set(offset)
+}
+
+// Snapshot is the complete engine state, including the position in the
+// rotation and schedule, so that another engine can continue from it.
+// Left is the time left in the current stage at the time Taken. Signals
+// counts the signals triggered so far and Signal is the latest one.
+type Snapshot struct {
+	State         State
+	Taken         time.Time
+	Left          time.Duration
+	RotationEnd   int
+	SessionIndex  int
+	DistanceIndex int
+	EndIndex      int
+	Action        int
+	Warn          int
+	Interrupted   Stage
+	ResumedLeft   time.Duration
+	Signals       uint64
+	Signal        Signal
+}
+
+func (e *Engine) Snapshot() Snapshot {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.clock.Now()
+	left := e.left
+	if !e.state.Paused {
+		left = e.deadline.Sub(now)
+	}
+	return Snapshot{
+		State:         e.state,
+		Taken:         now,
+		Left:          left,
+		RotationEnd:   e.end,
+		SessionIndex:  e.position.session,
+		DistanceIndex: e.position.distance,
+		EndIndex:      e.position.end,
+		Action:        e.action,
+		Warn:          e.warn,
+		Interrupted:   e.interrupted,
+		ResumedLeft:   e.resumed.Sub(now),
+		Signals:       e.signals,
+		Signal:        e.last,
+	}
+}
+
+// Restore replaces the engine state with a snapshot. It is used to follow
+// or take over another engine, which should run the same configuration; a
+// rotation or schedule position that does not exist in the configuration
+// of this engine starts over at the first one. Restore returns an event for
+// every change of stage, light, pause or duration and the signal of the
+// snapshot if it is new, shifted by the offset of this engine, so that a
+// lost event packet is made up by the next snapshot. The first snapshot
+// triggers no signal. Taken must be expressed in the clock of this engine;
+// a zero Taken counts Left from now.
+func (e *Engine) Restore(s Snapshot) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	previous := e.state
+	deadline := e.deadline
+	triggered := e.following && s.Signals != e.signals && s.Signals != 0
+	e.following = true
+	e.signals = s.Signals
+	e.last = s.Signal
+	e.state = s.State
+	e.end = s.RotationEnd
+	e.position = position{session: s.SessionIndex, distance: s.DistanceIndex, end: s.EndIndex}
+	if e.end < 0 || e.end >= len(e.config.Rotation) || e.state.Half < 0 || e.state.Half >= len(e.config.Rotation[e.end]) {
+		e.end = 0
+		e.state.Round = 0
+		e.state.Half = 0
+	}
+	e.state.Pair = e.pair()
+	if !e.schedule.contains(e.position) {
+		e.position = position{}
+	}
+	e.updateSchedule()
+	e.action = s.Action
+	e.warn = s.Warn
+	e.interrupted = s.Interrupted
+	taken := s.Taken
+	if taken.IsZero() {
+		taken = e.clock.Now()
+	}
+	e.resumed = taken.Add(s.ResumedLeft)
+	if s.State.Paused {
+		e.left = s.Left
+	} else {
+		e.deadline = taken.Add(s.Left)
+		if previous.Stage == e.state.Stage {
+			e.shift.move(deadline, e.deadline)
+		}
+	}
+	if previous.Stage != e.state.Stage {
+		e.emit(StageChanged)
+	}
+	if previous.Light != e.state.Light {
+		e.emit(LightChanged)
+	}
+	if previous.Paused != e.state.Paused {
+		e.emit(PauseChanged)
+	}
+	if previous.Duration != e.state.Duration {
+		e.emit(DurationChanged)
+	}
+	if triggered && e.shift.trigger(s.Signal, e.clock.Now()) {
+		e.emitSignal(s.Signal)
+	}
+	return e.flush()
+}
+
+// Countdown updates the seconds left in the current stage without any
+// transition. Engines that follow another engine call it instead of Tick;
+// like Tick it triggers the signals shifted by SetSignalOffset.
+func (e *Engine) Countdown() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shifted()
+	if e.state.Paused {
+		return e.flush()
+	}
+	switch e.state.Stage {
+	case Prepare, Action, Break:
+		e.setDuration(e.remaining())
+	}
+	e.anticipate()
+	return e.flush()
+}
+
+func (e *Engine) State() State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state
+}
+
+// Start begins the preparation phase of the next end, cutting a break
+// short. It is ignored while an end is running and after the schedule has
+// finished.
+func (e *Engine) Start() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state.Stage != Halt && e.state.Stage != Break {
+		return nil
+	}
+	e.action = e.config.ActionDuration
+	e.warn = e.config.WarnDuration
+	e.deadline = e.clock.Now()
+	e.startPrepare()
+	return e.flush()
+}
+
+// MakeUp starts a single end of arrows times seconds for the given targets,
+// e.g. after an equipment failure. It is accepted between ends, during a
+// break and after the schedule has finished; a running break goes on in
+// the background.
+func (e *Engine) MakeUp(arrows, seconds int, targets string) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state.Stage.Running() || arrows <= 0 || seconds <= 0 {
+		return nil
+	}
+	e.state.MakeUp = true
+	e.state.Targets = targets
+	e.startExtra(arrows*seconds, e.config.WarnDuration)
+	return e.flush()
+}
+
+// ShootOff starts a single arrow shoot-off of the given seconds without a
+// warning phase. It is accepted like MakeUp and keeps the schedule
+// position.
+func (e *Engine) ShootOff(seconds int) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state.Stage.Running() || seconds <= 0 {
+		return nil
+	}
+	e.state.ShootOff = true
+	e.startExtra(seconds, 0)
+	return e.flush()
+}
+
+// Cancel ends the shooting phase before its time is up.
+func (e *Engine) Cancel() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state.Stage != Action {
+		return nil
+	}
+	e.deadline = e.clock.Now()
+	e.finishAction()
+	return e.flush()
+}
+
+// Pause freezes a running preparation or shooting phase and triggers the
+// emergency signal.
+func (e *Engine) Pause() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.state.Paused || (e.state.Stage != Prepare && e.state.Stage != Action) {
+		return nil
+	}
+	e.left = e.deadline.Sub(e.clock.Now())
+	e.state.Paused = true
+	e.emit(PauseChanged)
+	e.setLight(Red)
+	e.signal(SignalEmergency)
+	return e.flush()
+}
+
+// Resume continues a paused phase with the time that was left.
+func (e *Engine) Resume() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.state.Paused {
+		return nil
+	}
+	deadline := e.deadline
+	e.deadline = e.clock.Now().Add(e.left)
+	e.shift.move(deadline, e.deadline)
+	e.state.Paused = false
+	e.emit(PauseChanged)
+	if e.state.Stage == Action {
+		if e.state.Duration <= e.warn {
+			e.setLight(Yellow)
+		} else {
+			e.setLight(Green)
+		}
+		e.signal(SignalStart)
+	}
+	return e.flush()
+}
+
+// Reset stops the engine and returns to the first round of the schedule.
+func (e *Engine) Reset() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shift.reset()
+	e.position = position{}
+	e.updateSchedule()
+	if e.state.Paused {
+		e.state.Paused = false
+		e.emit(PauseChanged)
+	}
+	e.state.MakeUp = false
+	e.state.ShootOff = false
+	e.state.Targets = ""
+	e.end = 0
+	e.state.Round = 0
+	e.state.Half = 0
+	e.state.Pair = e.pair()
+	e.setStage(Halt)
+	e.setLight(Red)
+	e.setDuration(0)
+	e.signal(SignalRestart)
+	return e.flush()
+}
+
+// Tick advances the engine to the current time of its clock. Transitions
+// are based on the deadline of the current stage, so a late tick never
+// skips the end of a stage. Tick also triggers the signals shifted by
+// SetSignalOffset.
+func (e *Engine) Tick() []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shifted()
+	if e.state.Paused {
+		return e.flush()
+	}
+	switch e.state.Stage {
+	case Prepare:
+		e.setDuration(e.remaining())
+		if e.state.Duration <= 0 {
+			e.signal(SignalStart)
+			e.startAction()
+		}
+	case Action:
+		e.setDuration(e.remaining())
+		if e.state.Duration <= 0 {
+			e.finishAction()
+		} else if e.state.Duration <= e.warn {
+			e.setLight(Yellow)
+		}
+	case Break:
+		e.setDuration(e.remaining())
+		if e.state.Duration <= 0 {
+			e.setStage(Halt)
+		}
+	}
+	e.anticipate()
+	return e.flush()
+}
+
+// shifted triggers the delayed signals whose time has come.
+func (e *Engine) shifted() {
+	for _, signal := range e.shift.due(e.clock.Now()) {
+		e.emitSignal(signal)
+	}
+}
+
+// anticipate triggers the signals at the end of a running stage ahead of
+// time.
+func (e *Engine) anticipate() {
+	switch e.state.Stage {
+	case Prepare, Action:
+		for _, signal := range e.shift.anticipate(e.deadline, e.clock.Now(), e.upcoming) {
+			e.emitSignal(signal)
+		}
+	}
+}
+
+// upcoming returns the signal of the transition at the end of the current
+// stage, following finishAction.
+func (e *Engine) upcoming() []Signal {
+	if e.state.Stage == Prepare {
+		return []Signal{SignalStart}
+	}
+	if e.state.MakeUp || e.state.ShootOff {
+		return []Signal{SignalEnd}
+	}
+	if e.state.Half+1 < len(e.config.Rotation[e.end]) {
+		return []Signal{SignalToLine}
+	}
+	if len(e.schedule) > 0 {
+		if _, _, last := e.schedule.next(e.position); last {
+			return []Signal{SignalFinal}
+		}
+	}
+	return []Signal{SignalEnd}
+}
+
+func (e *Engine) startPrepare() {
+	e.setStage(Prepare)
+	e.setLight(Red)
+	e.signal(SignalToLine)
+	if e.state.Half == 0 {
+		e.startCountdown(e.config.PrepareDuration[0])
+	} else {
+		e.startCountdown(e.config.PrepareDuration[1])
+	}
+}
+
+// startExtra prepares an ad-hoc end that leaves rotation and schedule
+// untouched.
+func (e *Engine) startExtra(action, warn int) {
+	e.interrupted = e.state.Stage
+	e.resumed = e.deadline
+	e.action = action
+	e.warn = warn
+	e.deadline = e.clock.Now()
+	e.setStage(Prepare)
+	e.setLight(Red)
+	e.signal(SignalToLine)
+	e.startCountdown(e.config.PrepareDuration[0])
+}
+
+func (e *Engine) startAction() {
+	e.setStage(Action)
+	e.setLight(Green)
+	e.startCountdown(e.action)
+}
+
+func (e *Engine) finishAction() {
+	if e.state.Paused {
+		e.state.Paused = false
+		e.emit(PauseChanged)
+	}
+	e.setLight(Red)
+	e.setDuration(0)
+	e.emit(EndFinished)
+	if e.state.MakeUp || e.state.ShootOff {
+		e.state.MakeUp = false
+		e.state.ShootOff = false
+		e.state.Targets = ""
+		e.setStage(e.interrupted)
+		if e.interrupted == Break {
+			e.deadline = e.resumed
+			e.setDuration(e.remaining())
+		}
+		e.signal(SignalEnd)
+		return
+	}
+	e.action = e.config.ActionDuration
+	e.warn = e.config.WarnDuration
+	e.state.Round++
+	if e.state.Half+1 < len(e.config.Rotation[e.end]) {
+		e.state.Half++
+		e.state.Pair = e.pair()
+		e.startPrepare()
+		return
+	}
+	e.state.Half = 0
+	e.end = (e.end + 1) % len(e.config.Rotation)
+	if e.end == 0 {
+		e.state.Round = 0
+	}
+	e.state.Pair = e.pair()
+	if len(e.schedule) == 0 {
+		e.setStage(Halt)
+		e.signal(SignalEnd)
+		return
+	}
+	next, pause, last := e.schedule.next(e.position)
+	if last {
+		e.setStage(Finished)
+		e.signal(SignalFinal)
+		return
+	}
+	e.position = next
+	e.updateSchedule()
+	e.signal(SignalEnd)
+	if pause > 0 {
+		e.setStage(Break)
+		e.startCountdown(pause)
+	} else {
+		e.setStage(Halt)
+	}
+}
+
+func (e *Engine) updateSchedule() {
+	if len(e.schedule) == 0 {
+		e.state.Session, e.state.Distance = "", ""
+		e.state.Practice, e.state.End, e.state.Ends = false, 0, 0
+		return
+	}
+	d := e.schedule.distance(e.position)
+	e.state.Session = e.schedule[e.position.session].Name
+	e.state.Distance = d.Name
+	e.state.Practice = e.position.end < d.PracticeEnds
+	if e.state.Practice {
+		e.state.End = e.position.end
+		e.state.Ends = d.PracticeEnds
+	} else {
+		e.state.End = e.position.end - d.PracticeEnds
+		e.state.Ends = d.Ends
+	}
+}
+
+func (e *Engine) pair() string {
+	return e.config.Rotation[e.end][e.state.Half]
+}
+
+// startCountdown starts the next stage at the deadline of the previous one,
+// so a late tick does not lengthen the end. Commands that start a stage set
+// the deadline to the current time first.
+func (e *Engine) startCountdown(seconds int) {
+	e.deadline = e.deadline.Add(time.Duration(seconds) * time.Second)
+	e.setDuration(e.remaining())
+}
+
+func (e *Engine) remaining() int {
+	return seconds(e.deadline.Sub(e.clock.Now()))
+}
+
+// seconds rounds a time left up to full seconds as shown on the display.
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+func (e *Engine) setStage(stage Stage) {
+	if e.state.Stage == stage {
+		return
+	}
+	e.state.Stage = stage
+	e.emit(StageChanged)
+}
+
+func (e *Engine) setLight(light Light) {
+	if e.state.Light == light {
+		return
+	}
+	e.state.Light = light
+	e.emit(LightChanged)
+}
+
+func (e *Engine) setDuration(duration int) {
+	if e.state.Duration == duration {
+		return
+	}
+	e.state.Duration = duration
+	e.emit(DurationChanged)
+}
+
+func (e *Engine) signal(signal Signal) {
+	e.signals++
+	e.last = signal
+	if e.shift.trigger(signal, e.clock.Now()) {
+		e.emitSignal(signal)
+	}
+}
+
+func (e *Engine) emitSignal(signal Signal) {
+	e.events = append(e.events, Event{Type: SignalTriggered, State: e.state, Signal: signal})
+}
+
+func (e *Engine) emit(t EventType) {
+	e.events = append(e.events, Event{Type: t, State: e.state})
+}
+
+func (e *Engine) flush() []Event {
+	events := e.events
+	e.events = nil
+	return events
+}
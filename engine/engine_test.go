@@ -0,0 +1,406 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// step advances the fake clock, runs do (Tick if nil) and checks the state
+// and the signals triggered by it.
+type step struct {
+	advance  time.Duration
+	do       func(e *Engine) []Event
+	stage    Stage
+	light    Light
+	duration int
+	pair     string
+	round    int
+	paused   bool
+	distance string
+	end      int
+	practice bool
+	signals  []Signal
+}
+
+func start(e *Engine) []Event    { return e.Start() }
+func cancel(e *Engine) []Event   { return e.Cancel() }
+func pause(e *Engine) []Event    { return e.Pause() }
+func resume(e *Engine) []Event   { return e.Resume() }
+func reset(e *Engine) []Event    { return e.Reset() }
+func makeUp(e *Engine) []Event   { return e.MakeUp(1, 10, "3") }
+func shootOff(e *Engine) []Event { return e.ShootOff(5) }
+
+func TestEngine(t *testing.T) {
+	config := Config{ActionDuration: 10, WarnDuration: 3, PrepareDuration: [2]int{2, 4}, Rotation: TwoDetails}
+	threeDetails := config
+	threeDetails.Rotation = ThreeDetails
+	final := Schedule{{Name: "Qualification", Distances: []Distance{{Name: "18m", Ends: 1}}}}
+	singleDetail := config
+	singleDetail.Rotation = SingleDetail
+	twoDistances := Schedule{{Name: "Qualification", Distances: []Distance{
+		{Name: "18m", PracticeEnds: 1, Ends: 2, Break: 30},
+		{Name: "25m", Ends: 1},
+	}}}
+	firstDistance := []step{
+		{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-D", distance: "18m", practice: true, signals: []Signal{SignalToLine}},
+		{advance: 2 * time.Second, stage: Action, light: Green, duration: 10, pair: "A-D", distance: "18m", practice: true, signals: []Signal{SignalStart}},
+		{do: cancel, stage: Halt, light: Red, pair: "A-D", distance: "18m", signals: []Signal{SignalEnd}},
+		{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-D", distance: "18m", signals: []Signal{SignalToLine}},
+		{advance: 2 * time.Second, stage: Action, light: Green, duration: 10, pair: "A-D", distance: "18m", signals: []Signal{SignalStart}},
+		{do: cancel, stage: Halt, light: Red, pair: "A-D", distance: "18m", end: 1, signals: []Signal{SignalEnd}},
+		{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-D", distance: "18m", end: 1, signals: []Signal{SignalToLine}},
+		{advance: 2 * time.Second, stage: Action, light: Green, duration: 10, pair: "A-D", distance: "18m", end: 1, signals: []Signal{SignalStart}},
+		{do: cancel, stage: Break, light: Red, duration: 30, pair: "A-D", distance: "25m", signals: []Signal{SignalEnd}},
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		schedule Schedule
+		offset   time.Duration
+		steps    []step
+	}{
+		{
+			name:   "end with two details",
+			config: config,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: time.Second, stage: Prepare, light: Red, duration: 1, pair: "A-B"},
+				{advance: time.Second, stage: Action, light: Green, duration: 10, pair: "A-B", signals: []Signal{SignalStart}},
+				{advance: 7 * time.Second, stage: Action, light: Yellow, duration: 3, pair: "A-B"},
+				{advance: 3 * time.Second, stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1, signals: []Signal{SignalToLine}},
+				{advance: 4 * time.Second, stage: Action, light: Green, duration: 10, pair: "C-D", round: 1, signals: []Signal{SignalStart}},
+				{advance: 10 * time.Second, stage: Halt, light: Red, pair: "C-D", round: 2, signals: []Signal{SignalEnd}},
+			},
+		},
+		{
+			name:   "late tick keeps the deadline",
+			config: config,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: 3 * time.Second, stage: Action, light: Green, duration: 9, pair: "A-B", signals: []Signal{SignalStart}},
+				{advance: 9 * time.Second, stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1, signals: []Signal{SignalToLine}},
+			},
+		},
+		{
+			name:   "cancel",
+			config: config,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: 2 * time.Second, stage: Action, light: Green, duration: 10, pair: "A-B", signals: []Signal{SignalStart}},
+				{advance: 5 * time.Second, do: cancel, stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1, signals: []Signal{SignalToLine}},
+				{advance: 4 * time.Second, stage: Action, light: Green, duration: 10, pair: "C-D", round: 1, signals: []Signal{SignalStart}},
+				{do: cancel, stage: Halt, light: Red, pair: "C-D", round: 2, signals: []Signal{SignalEnd}},
+				{do: cancel, stage: Halt, light: Red, pair: "C-D", round: 2},
+			},
+		},
+		{
+			name:   "pause and resume",
+			config: config,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: 2 * time.Second, stage: Action, light: Green, duration: 10, pair: "A-B", signals: []Signal{SignalStart}},
+				{advance: 5 * time.Second, do: pause, stage: Action, light: Red, duration: 10, pair: "A-B", paused: true, signals: []Signal{SignalEmergency}},
+				{advance: time.Minute, stage: Action, light: Red, duration: 10, pair: "A-B", paused: true},
+				{do: resume, stage: Action, light: Green, duration: 10, pair: "A-B", signals: []Signal{SignalStart}},
+				{advance: time.Second, stage: Action, light: Green, duration: 4, pair: "A-B"},
+				{advance: 2 * time.Second, stage: Action, light: Yellow, duration: 2, pair: "A-B"},
+				{do: pause, stage: Action, light: Red, duration: 2, pair: "A-B", paused: true, signals: []Signal{SignalEmergency}},
+				{do: resume, stage: Action, light: Yellow, duration: 2, pair: "A-B", signals: []Signal{SignalStart}},
+				{advance: 2 * time.Second, stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1, signals: []Signal{SignalToLine}},
+			},
+		},
+		{
+			name:   "reset",
+			config: config,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: 12 * time.Second, stage: Action, light: Green, duration: 0, pair: "A-B", signals: []Signal{SignalStart}},
+				{stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1, signals: []Signal{SignalToLine}},
+				{do: reset, stage: Halt, light: Red, pair: "A-B", signals: []Signal{SignalRestart}},
+				{advance: time.Minute, stage: Halt, light: Red, pair: "A-B"},
+			},
+		},
+		{
+			name:   "rotation wraps",
+			config: threeDetails,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: 12 * time.Second, stage: Action, light: Green, duration: 0, pair: "A-B", signals: []Signal{SignalStart}},
+				{stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1, signals: []Signal{SignalToLine}},
+				{advance: 14 * time.Second, stage: Action, light: Green, duration: 0, pair: "C-D", round: 1, signals: []Signal{SignalStart}},
+				{stage: Prepare, light: Red, duration: 4, pair: "E-F", round: 2, signals: []Signal{SignalToLine}},
+				{advance: 14 * time.Second, stage: Action, light: Green, duration: 0, pair: "E-F", round: 2, signals: []Signal{SignalStart}},
+				{stage: Halt, light: Red, pair: "A-B", signals: []Signal{SignalEnd}},
+			},
+		},
+		{
+			name:     "final end",
+			config:   config,
+			schedule: final,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", distance: "18m", signals: []Signal{SignalToLine}},
+				{advance: 12 * time.Second, stage: Action, light: Green, duration: 0, pair: "A-B", distance: "18m", signals: []Signal{SignalStart}},
+				{stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1, distance: "18m", signals: []Signal{SignalToLine}},
+				{advance: 14 * time.Second, stage: Action, light: Green, duration: 0, pair: "C-D", round: 1, distance: "18m", signals: []Signal{SignalStart}},
+				{stage: Finished, light: Red, pair: "C-D", round: 2, distance: "18m", signals: []Signal{SignalFinal}},
+				{do: start, stage: Finished, light: Red, pair: "C-D", round: 2, distance: "18m"},
+			},
+		},
+		{
+			name:     "shoot-off after the final end",
+			config:   config,
+			schedule: final,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", distance: "18m", signals: []Signal{SignalToLine}},
+				{advance: 12 * time.Second, stage: Action, light: Green, duration: 0, pair: "A-B", distance: "18m", signals: []Signal{SignalStart}},
+				{stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1, distance: "18m", signals: []Signal{SignalToLine}},
+				{advance: 14 * time.Second, stage: Action, light: Green, duration: 0, pair: "C-D", round: 1, distance: "18m", signals: []Signal{SignalStart}},
+				{stage: Finished, light: Red, pair: "C-D", round: 2, distance: "18m", signals: []Signal{SignalFinal}},
+				{do: shootOff, stage: Prepare, light: Red, duration: 2, pair: "C-D", round: 2, distance: "18m", signals: []Signal{SignalToLine}},
+				{advance: 2 * time.Second, stage: Action, light: Green, duration: 5, pair: "C-D", round: 2, distance: "18m", signals: []Signal{SignalStart}},
+				{advance: 5 * time.Second, stage: Finished, light: Red, pair: "C-D", round: 2, distance: "18m", signals: []Signal{SignalEnd}},
+				{do: start, stage: Finished, light: Red, pair: "C-D", round: 2, distance: "18m"},
+			},
+		},
+		{
+			name:     "break after a distance",
+			config:   singleDetail,
+			schedule: twoDistances,
+			steps: append(firstDistance[:len(firstDistance):len(firstDistance)],
+				step{advance: 2 * time.Second, stage: Break, light: Red, duration: 28, pair: "A-D", distance: "25m"},
+				step{advance: 28 * time.Second, stage: Halt, light: Red, pair: "A-D", distance: "25m"},
+				step{advance: time.Minute, stage: Halt, light: Red, pair: "A-D", distance: "25m"},
+			),
+		},
+		{
+			name:     "start cuts a break short",
+			config:   singleDetail,
+			schedule: twoDistances,
+			steps: append(firstDistance[:len(firstDistance):len(firstDistance)],
+				step{advance: 2 * time.Second, stage: Break, light: Red, duration: 28, pair: "A-D", distance: "25m"},
+				step{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-D", distance: "25m", signals: []Signal{SignalToLine}},
+				step{advance: 2 * time.Second, stage: Action, light: Green, duration: 10, pair: "A-D", distance: "25m", signals: []Signal{SignalStart}},
+				step{advance: 10 * time.Second, stage: Finished, light: Red, pair: "A-D", distance: "25m", signals: []Signal{SignalFinal}},
+			),
+		},
+		{
+			name:     "make-up end during a break",
+			config:   singleDetail,
+			schedule: twoDistances,
+			steps: append(firstDistance[:len(firstDistance):len(firstDistance)],
+				step{do: makeUp, stage: Prepare, light: Red, duration: 2, pair: "A-D", distance: "25m", signals: []Signal{SignalToLine}},
+				step{advance: 2 * time.Second, stage: Action, light: Green, duration: 10, pair: "A-D", distance: "25m", signals: []Signal{SignalStart}},
+				step{advance: 10 * time.Second, stage: Break, light: Red, duration: 18, pair: "A-D", distance: "25m", signals: []Signal{SignalEnd}},
+				step{advance: 18 * time.Second, stage: Halt, light: Red, pair: "A-D", distance: "25m"},
+			),
+		},
+		{
+			name:   "signals ahead of the light",
+			config: config,
+			offset: 300 * time.Millisecond,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: 1700 * time.Millisecond, stage: Prepare, light: Red, duration: 1, pair: "A-B", signals: []Signal{SignalStart}},
+				{advance: 300 * time.Millisecond, stage: Action, light: Green, duration: 10, pair: "A-B"},
+				{advance: 9700 * time.Millisecond, stage: Action, light: Yellow, duration: 1, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: 300 * time.Millisecond, stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1},
+				{advance: 3700 * time.Millisecond, stage: Prepare, light: Red, duration: 1, pair: "C-D", round: 1, signals: []Signal{SignalStart}},
+				{do: reset, stage: Halt, light: Red, pair: "A-B", signals: []Signal{SignalRestart}},
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+			},
+		},
+		{
+			name:   "pause after a signal ahead of the light",
+			config: config,
+			offset: 300 * time.Millisecond,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: 2 * time.Second, stage: Action, light: Green, duration: 10, pair: "A-B", signals: []Signal{SignalStart}},
+				{advance: 9800 * time.Millisecond, stage: Action, light: Yellow, duration: 1, pair: "A-B", signals: []Signal{SignalToLine}},
+				{do: pause, stage: Action, light: Red, duration: 1, pair: "A-B", paused: true, signals: []Signal{SignalEmergency}},
+				{advance: time.Minute, do: resume, stage: Action, light: Yellow, duration: 1, pair: "A-B", signals: []Signal{SignalStart}},
+				{stage: Action, light: Yellow, duration: 1, pair: "A-B"},
+				{advance: 200 * time.Millisecond, stage: Prepare, light: Red, duration: 4, pair: "C-D", round: 1},
+			},
+		},
+		{
+			name:   "signals after the light",
+			config: config,
+			offset: -300 * time.Millisecond,
+			steps: []step{
+				{do: start, stage: Prepare, light: Red, duration: 2, pair: "A-B"},
+				{advance: 300 * time.Millisecond, stage: Prepare, light: Red, duration: 2, pair: "A-B", signals: []Signal{SignalToLine}},
+				{advance: 1700 * time.Millisecond, stage: Action, light: Green, duration: 10, pair: "A-B"},
+				{advance: 300 * time.Millisecond, stage: Action, light: Green, duration: 10, pair: "A-B", signals: []Signal{SignalStart}},
+				{do: pause, stage: Action, light: Red, duration: 10, pair: "A-B", paused: true},
+				{advance: 300 * time.Millisecond, stage: Action, light: Red, duration: 10, pair: "A-B", paused: true, signals: []Signal{SignalEmergency}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2021, 6, 5, 9, 0, 0, 0, time.UTC)}
+			e := New(test.config, clock)
+			e.SetSignalOffset(test.offset)
+			if test.schedule != nil {
+				if err := e.SetSchedule(test.schedule); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i, s := range test.steps {
+				clock.now = clock.now.Add(s.advance)
+				do := s.do
+				if do == nil {
+					do = (*Engine).Tick
+				}
+				var signals []Signal
+				for _, event := range do(e) {
+					if event.Type == SignalTriggered {
+						signals = append(signals, event.Signal)
+					}
+				}
+				state := e.State()
+				if state.Stage != s.stage || state.Light != s.light || state.Duration != s.duration ||
+					state.Pair != s.pair || state.Round != s.round || state.Paused != s.paused {
+					t.Errorf("step %d: got %v %v %ds %s round %d paused %v, want %v %v %ds %s round %d paused %v", i,
+						state.Stage, state.Light, state.Duration, state.Pair, state.Round, state.Paused,
+						s.stage, s.light, s.duration, s.pair, s.round, s.paused)
+				}
+				if state.Distance != s.distance || state.End != s.end || state.Practice != s.practice {
+					t.Errorf("step %d: got %q end %d practice %v, want %q end %d practice %v", i,
+						state.Distance, state.End, state.Practice, s.distance, s.end, s.practice)
+				}
+				if !reflect.DeepEqual(signals, s.signals) {
+					t.Errorf("step %d: got signals %v, want %v", i, signals, s.signals)
+				}
+			}
+		})
+	}
+}
+
+func TestSetScheduleRejectsEmptySession(t *testing.T) {
+	e := New(DefaultConfig(), &fakeClock{})
+	valid := Schedule{{Name: "Qualification", Distances: []Distance{{Name: "70m", Ends: 6}}}}
+	if err := e.SetSchedule(valid); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetSchedule(Schedule{{Name: "Finals"}}); err == nil {
+		t.Error("schedule with an empty session accepted")
+	}
+	if err := e.SetSchedule(Schedule{{Name: "Finals", Distances: []Distance{{Name: "70m"}}}}); err == nil {
+		t.Error("distance without ends accepted")
+	}
+	if state := e.State(); state.Session != "Qualification" || state.Ends != 6 {
+		t.Errorf("got %s with %d ends, want the previous schedule", state.Session, state.Ends)
+	}
+}
+
+func TestRestoreEmitsChanges(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2021, 6, 5, 9, 0, 0, 0, time.UTC)}
+	master := New(DefaultConfig(), clock)
+	follower := New(DefaultConfig(), clock)
+	master.Start()
+	clock.now = clock.now.Add(10 * time.Second)
+	master.Tick()
+
+	var types []EventType
+	for _, event := range follower.Restore(master.Snapshot()) {
+		types = append(types, event.Type)
+	}
+	want := []EventType{StageChanged, LightChanged, DurationChanged}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("got events %v, want %v", types, want)
+	}
+	if events := follower.Restore(master.Snapshot()); len(events) != 0 {
+		t.Errorf("got events %v for an unchanged snapshot", events)
+	}
+	if state := follower.State(); state != master.State() {
+		t.Errorf("got state %+v, want %+v", state, master.State())
+	}
+}
+
+func TestRestoreForeignPosition(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2021, 6, 5, 9, 0, 0, 0, time.UTC)}
+	master := New(Config{ActionDuration: 10, WarnDuration: 3, PrepareDuration: [2]int{2, 4}, Rotation: TwoDetails}, clock)
+	if err := master.SetSchedule(Schedule{{Name: "Qualification", Distances: []Distance{{Name: "70m", Ends: 6}}}}); err != nil {
+		t.Fatal(err)
+	}
+	master.Start()
+	clock.now = clock.now.Add(12 * time.Second)
+	master.Tick()
+	master.Tick()
+	clock.now = clock.now.Add(14 * time.Second)
+	master.Tick()
+	master.Tick()
+	master.Start()
+	snapshot := master.Snapshot()
+	if snapshot.RotationEnd != 1 || snapshot.EndIndex != 1 {
+		t.Fatalf("master at rotation end %d, end %d, want 1 and 1", snapshot.RotationEnd, snapshot.EndIndex)
+	}
+
+	backup := New(Config{ActionDuration: 10, WarnDuration: 3, PrepareDuration: [2]int{2, 4}, Rotation: SingleDetail}, clock)
+	backup.Restore(snapshot)
+	if state := backup.State(); state.Pair != "A-D" || state.Half != 0 || state.Ends != 0 {
+		t.Errorf("got pair %s half %d with %d ends, want the first end of the backup", state.Pair, state.Half, state.Ends)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	backup.Tick()
+	backup.Tick()
+	if state := backup.State(); state.Stage != Halt {
+		t.Errorf("got stage %v, want %v", state.Stage, Halt)
+	}
+}
+
+func TestFollowerShiftsSignals(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration
+		// signals are the signals of the follower at 0, 1.7, 1.8, 2 and
+		// 2.3 seconds after the start.
+		signals [][]Signal
+	}{
+		{name: "no offset", signals: [][]Signal{{SignalToLine}, nil, nil, {SignalStart}, nil}},
+		{name: "ahead of the light", offset: 300 * time.Millisecond, signals: [][]Signal{{SignalToLine}, {SignalStart}, nil, nil, nil}},
+		{name: "after the light", offset: -300 * time.Millisecond, signals: [][]Signal{nil, {SignalToLine}, nil, nil, {SignalStart}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2021, 6, 5, 9, 0, 0, 0, time.UTC)}
+			config := Config{ActionDuration: 10, WarnDuration: 3, PrepareDuration: [2]int{2, 4}, Rotation: TwoDetails}
+			master := New(config, clock)
+			follower := New(config, clock)
+			follower.SetSignalOffset(test.offset)
+			follower.Restore(master.Snapshot())
+			master.Start()
+
+			// The snapshots jitter by a few milliseconds like the measured
+			// clock offset of a real follower.
+			for i, advance := range []time.Duration{0, 1700, 100, 200, 300} {
+				clock.now = clock.now.Add(advance * time.Millisecond)
+				master.Tick()
+				snapshot := master.Snapshot()
+				snapshot.Taken = snapshot.Taken.Add(-time.Duration(i%2*3) * time.Millisecond)
+				var signals []Signal
+				for _, event := range append(follower.Restore(snapshot), follower.Countdown()...) {
+					if event.Type == SignalTriggered {
+						signals = append(signals, event.Signal)
+					}
+				}
+				if !reflect.DeepEqual(signals, test.signals[i]) {
+					t.Errorf("step %d: got signals %v, want %v", i, signals, test.signals[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package engine
+
+type EventType int
+
+const (
+	StageChanged EventType = iota
+	LightChanged
+	DurationChanged
+	PauseChanged
+	SignalTriggered
+	// EndFinished is emitted when a detail has finished shooting; State is
+	// the one of the finished end.
+	EndFinished
+	TurnChanged
+	ScoreChanged
+)
+
+var eventNames = [...]string{"stage", "light", "duration", "pause", "signal", "endFinished", "turn", "score"}
+
+func (t EventType) String() string {
+	if t < 0 || int(t) >= len(eventNames) {
+		return "unknown"
+	}
+	return eventNames[t]
+}
+
+// Signal is the role of an acoustic signal, independent of the sound used
+// to play it.
+type Signal int
+
+const (
+	SignalToLine Signal = iota
+	SignalStart
+	SignalEnd
+	SignalRestart
+	SignalFinal
+	SignalEmergency
+	SignalTimeout
+)
+
+var signalNames = [...]string{"toLine", "start", "end", "restart", "final", "emergency", "timeout"}
+
+func (s Signal) String() string {
+	if s < 0 || int(s) >= len(signalNames) {
+		return "unknown"
+	}
+	return signalNames[s]
+}
+
+// Event is emitted by the Engine on every transition. State is the engine
+// state right after the transition; Signal is only set for SignalTriggered.
+type Event struct {
+	Type   EventType
+	State  State
+	Signal Signal
+}
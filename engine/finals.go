@@ -0,0 +1,326 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+type Side int
+
+const (
+	Left Side = iota
+	Right
+)
+
+func (s Side) Other() Side {
+	return 1 - s
+}
+
+type FinalsMode int
+
+const (
+	Individual FinalsMode = iota
+	Team
+)
+
+// MatchConfig holds the timing of an alternate shooting match. In
+// individual mode every turn has TurnDuration seconds; in team mode a side
+// shares Budget seconds over all of its turns in a set.
+type MatchConfig struct {
+	Mode         FinalsMode
+	TurnDuration int
+	Budget       int
+	Turns        int
+	WarnDuration int
+}
+
+func IndividualFinals() MatchConfig {
+	return MatchConfig{Mode: Individual, TurnDuration: 20, Turns: 3}
+}
+
+func TeamFinals() MatchConfig {
+	return MatchConfig{Mode: Team, Budget: 120, Turns: 2, WarnDuration: 30}
+}
+
+// ShootOffFinals is a single arrow shoot-off with alternating opponents:
+// 40 seconds for an individual archer, 60 seconds for a team.
+func ShootOffFinals(team bool) MatchConfig {
+	if team {
+		return MatchConfig{Mode: Team, Budget: 60, Turns: 1}
+	}
+	return MatchConfig{Mode: Individual, TurnDuration: 40, Turns: 1}
+}
+
+// MatchState is a snapshot of a match. Duration holds the seconds left on
+// the clock of each side, Turns the turns each side has left in the set.
+type MatchState struct {
+	Mode     FinalsMode
+	Running  bool
+	Active   Side
+	Duration [2]int
+	Turns    [2]int
+	Points   [2]int
+	Set      int
+	Light    [2]Light
+}
+
+type MatchEvent struct {
+	Type   EventType
+	State  MatchState
+	Signal Signal
+}
+
+// Match runs the two clocks of an alternate shooting final. Only the clock
+// of the active side runs; passing the turn or a time-out hands over to the
+// other side. All methods are safe for concurrent use.
+type Match struct {
+	mu       sync.Mutex
+	config   MatchConfig
+	clock    Clock
+	state    MatchState
+	left     [2]time.Duration
+	deadline time.Time
+	shift    signalShift
+	events   []MatchEvent
+}
+
+func NewMatch(config MatchConfig, clock Clock) *Match {
+	if clock == nil {
+		clock = SystemClock()
+	}
+	m := &Match{clock: clock}
+	m.reset(config)
+	return m
+}
+
+func (m *Match) Config() MatchConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.config
+}
+
+// SetConfig switches the match format and starts a new match.
+func (m *Match) SetConfig(config MatchConfig) []MatchEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reset(config)
+	m.emit(StageChanged)
+	return m.flush()
+}
+
+// SetSignalOffset shifts the signals like Engine.SetSignalOffset; the
+// time-out of a turn is anticipated.
+func (m *Match) SetSignalOffset(offset time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shift.set(offset)
+}
+
+func (m *Match) State() MatchState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// Start begins the next set with first as the active side.
+func (m *Match) Start(first Side) []MatchEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state.Running {
+		return nil
+	}
+	m.state.Set++
+	m.state.Running = true
+	for _, side := range []Side{Left, Right} {
+		m.state.Turns[side] = m.config.Turns
+		m.left[side] = time.Duration(m.config.Budget) * time.Second
+		if m.config.Mode == Individual {
+			m.left[side] = time.Duration(m.config.TurnDuration) * time.Second
+		}
+		m.state.Duration[side] = seconds(m.left[side])
+	}
+	m.emit(StageChanged)
+	m.signal(SignalStart)
+	m.startTurn(first)
+	return m.flush()
+}
+
+// Pass ends the turn of the active side.
+func (m *Match) Pass() []MatchEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.state.Running {
+		return nil
+	}
+	m.endTurn()
+	return m.flush()
+}
+
+// Stop aborts the running set.
+func (m *Match) Stop() []MatchEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.state.Running {
+		return nil
+	}
+	m.finishSet()
+	return m.flush()
+}
+
+// AddPoints adds set points to a side; negative points correct a mistake.
+func (m *Match) AddPoints(side Side, points int) []MatchEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state.Points[side]+points < 0 {
+		return nil
+	}
+	m.state.Points[side] += points
+	m.emit(ScoreChanged)
+	return m.flush()
+}
+
+// Reset clears the set points and starts a new match.
+func (m *Match) Reset() []MatchEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reset(m.config)
+	m.emit(StageChanged)
+	m.signal(SignalRestart)
+	return m.flush()
+}
+
+// Tick advances the clock of the active side and hands over the turn when
+// its time is up.
+func (m *Match) Tick() []MatchEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, signal := range m.shift.due(m.clock.Now()) {
+		m.emitSignal(signal)
+	}
+	if !m.state.Running {
+		return m.flush()
+	}
+	active := m.state.Active
+	m.setDuration(active, seconds(m.deadline.Sub(m.clock.Now())))
+	if m.state.Duration[active] <= 0 {
+		m.signal(SignalTimeout)
+		m.endTurn()
+	} else if m.state.Duration[active] <= m.config.WarnDuration {
+		m.setLight(active, Yellow)
+	}
+	if m.state.Running {
+		for _, signal := range m.shift.anticipate(m.deadline, m.clock.Now(), m.upcoming) {
+			m.emitSignal(signal)
+		}
+	}
+	return m.flush()
+}
+
+// upcoming returns the signals of a time-out of the active side, following
+// endTurn.
+func (m *Match) upcoming() []Signal {
+	active := m.state.Active
+	again := m.config.Mode == Individual && m.state.Turns[active] > 1
+	if again || m.canShoot(active.Other()) {
+		return []Signal{SignalTimeout}
+	}
+	return []Signal{SignalTimeout, SignalEnd}
+}
+
+func (m *Match) reset(config MatchConfig) {
+	m.config = config
+	m.shift.reset()
+	m.state = MatchState{Mode: config.Mode, Light: [2]Light{Red, Red}}
+	if config.Mode == Individual {
+		m.state.Duration = [2]int{config.TurnDuration, config.TurnDuration}
+	} else {
+		m.state.Duration = [2]int{config.Budget, config.Budget}
+	}
+}
+
+func (m *Match) startTurn(side Side) {
+	m.state.Active = side
+	m.deadline = m.clock.Now().Add(m.left[side])
+	m.emit(TurnChanged)
+	if m.state.Duration[side] <= m.config.WarnDuration {
+		m.setLight(side, Yellow)
+	} else {
+		m.setLight(side, Green)
+	}
+}
+
+func (m *Match) endTurn() {
+	active := m.state.Active
+	m.left[active] = m.deadline.Sub(m.clock.Now())
+	if m.left[active] < 0 {
+		m.left[active] = 0
+	}
+	m.setDuration(active, seconds(m.left[active]))
+	m.state.Turns[active]--
+	if m.config.Mode == Team && m.left[active] == 0 {
+		m.state.Turns[active] = 0
+	}
+	m.setLight(active, Red)
+
+	next := active.Other()
+	if !m.canShoot(next) {
+		next = active
+	}
+	if !m.canShoot(next) {
+		m.finishSet()
+		return
+	}
+	if m.config.Mode == Individual {
+		m.left[next] = time.Duration(m.config.TurnDuration) * time.Second
+		m.setDuration(next, m.config.TurnDuration)
+	}
+	m.startTurn(next)
+}
+
+func (m *Match) canShoot(side Side) bool {
+	return m.state.Turns[side] > 0 && (m.config.Mode == Individual || m.left[side] > 0)
+}
+
+func (m *Match) finishSet() {
+	m.state.Running = false
+	m.setLight(Left, Red)
+	m.setLight(Right, Red)
+	m.emit(StageChanged)
+	m.signal(SignalEnd)
+}
+
+func (m *Match) setDuration(side Side, duration int) {
+	if m.state.Duration[side] == duration {
+		return
+	}
+	m.state.Duration[side] = duration
+	m.emit(DurationChanged)
+}
+
+func (m *Match) setLight(side Side, light Light) {
+	if m.state.Light[side] == light {
+		return
+	}
+	m.state.Light[side] = light
+	m.emit(LightChanged)
+}
+
+func (m *Match) signal(signal Signal) {
+	if m.shift.trigger(signal, m.clock.Now()) {
+		m.emitSignal(signal)
+	}
+}
+
+func (m *Match) emitSignal(signal Signal) {
+	m.events = append(m.events, MatchEvent{Type: SignalTriggered, State: m.state, Signal: signal})
+}
+
+func (m *Match) emit(t EventType) {
+	m.events = append(m.events, MatchEvent{Type: t, State: m.state})
+}
+
+func (m *Match) flush() []MatchEvent {
+	events := m.events
+	m.events = nil
+	return events
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// matchStep advances the fake clock, runs do (Tick if nil) and checks the
+// match state and the signals triggered by it.
+type matchStep struct {
+	advance  time.Duration
+	do       func(m *Match) []MatchEvent
+	running  bool
+	active   Side
+	duration [2]int
+	turns    [2]int
+	points   [2]int
+	light    [2]Light
+	signals  []Signal
+}
+
+func startLeft(m *Match) []MatchEvent { return m.Start(Left) }
+func pass(m *Match) []MatchEvent      { return m.Pass() }
+
+func addPoints(side Side, points int) func(m *Match) []MatchEvent {
+	return func(m *Match) []MatchEvent { return m.AddPoints(side, points) }
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		config MatchConfig
+		offset time.Duration
+		steps  []matchStep
+	}{
+		{
+			name:   "individual turns",
+			config: IndividualFinals(),
+			steps: []matchStep{
+				{do: startLeft, running: true, active: Left, duration: [2]int{20, 20}, turns: [2]int{3, 3}, light: [2]Light{Green, Red}, signals: []Signal{SignalStart}},
+				{advance: 5 * time.Second, do: pass, running: true, active: Right, duration: [2]int{15, 20}, turns: [2]int{2, 3}, light: [2]Light{Red, Green}},
+				{advance: 10 * time.Second, running: true, active: Right, duration: [2]int{15, 10}, turns: [2]int{2, 3}, light: [2]Light{Red, Green}},
+				{advance: 10 * time.Second, running: true, active: Left, duration: [2]int{20, 0}, turns: [2]int{2, 2}, light: [2]Light{Green, Red}, signals: []Signal{SignalTimeout}},
+				{do: pass, running: true, active: Right, duration: [2]int{20, 20}, turns: [2]int{1, 2}, light: [2]Light{Red, Green}},
+				{do: pass, running: true, active: Left, duration: [2]int{20, 20}, turns: [2]int{1, 1}, light: [2]Light{Green, Red}},
+				{do: pass, running: true, active: Right, duration: [2]int{20, 20}, turns: [2]int{0, 1}, light: [2]Light{Red, Green}},
+				{do: pass, active: Right, duration: [2]int{20, 20}, light: [2]Light{Red, Red}, signals: []Signal{SignalEnd}},
+				{do: pass, active: Right, duration: [2]int{20, 20}, light: [2]Light{Red, Red}},
+			},
+		},
+		{
+			name:   "team budget carries over",
+			config: TeamFinals(),
+			steps: []matchStep{
+				{do: startLeft, running: true, active: Left, duration: [2]int{120, 120}, turns: [2]int{2, 2}, light: [2]Light{Green, Red}, signals: []Signal{SignalStart}},
+				{advance: 50 * time.Second, do: pass, running: true, active: Right, duration: [2]int{70, 120}, turns: [2]int{1, 2}, light: [2]Light{Red, Green}},
+				{advance: 100 * time.Second, running: true, active: Right, duration: [2]int{70, 20}, turns: [2]int{1, 2}, light: [2]Light{Red, Yellow}},
+				{do: pass, running: true, active: Left, duration: [2]int{70, 20}, turns: [2]int{1, 1}, light: [2]Light{Green, Red}},
+				{advance: 70 * time.Second, running: true, active: Right, duration: [2]int{0, 20}, turns: [2]int{0, 1}, light: [2]Light{Red, Yellow}, signals: []Signal{SignalTimeout}},
+				{advance: 20 * time.Second, active: Right, duration: [2]int{0, 0}, light: [2]Light{Red, Red}, signals: []Signal{SignalTimeout, SignalEnd}},
+			},
+		},
+		{
+			name:   "set points",
+			config: IndividualFinals(),
+			steps: []matchStep{
+				{do: addPoints(Left, 2), duration: [2]int{20, 20}, points: [2]int{2, 0}},
+				{do: addPoints(Left, -3), duration: [2]int{20, 20}, points: [2]int{2, 0}},
+				{do: addPoints(Right, -1), duration: [2]int{20, 20}, points: [2]int{2, 0}},
+				{do: addPoints(Left, -2), duration: [2]int{20, 20}},
+			},
+		},
+		{
+			name:   "signals ahead of the time-out",
+			config: ShootOffFinals(false),
+			offset: 300 * time.Millisecond,
+			steps: []matchStep{
+				{do: startLeft, running: true, active: Left, duration: [2]int{40, 40}, turns: [2]int{1, 1}, light: [2]Light{Green, Red}, signals: []Signal{SignalStart}},
+				{advance: 39700 * time.Millisecond, running: true, active: Left, duration: [2]int{1, 40}, turns: [2]int{1, 1}, light: [2]Light{Green, Red}, signals: []Signal{SignalTimeout}},
+				{advance: 300 * time.Millisecond, running: true, active: Right, duration: [2]int{0, 40}, turns: [2]int{0, 1}, light: [2]Light{Red, Green}},
+				{advance: 39700 * time.Millisecond, running: true, active: Right, duration: [2]int{0, 1}, turns: [2]int{0, 1}, light: [2]Light{Red, Green}, signals: []Signal{SignalTimeout, SignalEnd}},
+				{advance: 300 * time.Millisecond, active: Right, light: [2]Light{Red, Red}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2021, 6, 5, 9, 0, 0, 0, time.UTC)}
+			m := NewMatch(test.config, clock)
+			m.SetSignalOffset(test.offset)
+			for i, s := range test.steps {
+				clock.now = clock.now.Add(s.advance)
+				do := s.do
+				if do == nil {
+					do = (*Match).Tick
+				}
+				var signals []Signal
+				for _, event := range do(m) {
+					if event.Type == SignalTriggered {
+						signals = append(signals, event.Signal)
+					}
+				}
+				state := m.State()
+				if state.Running != s.running || state.Active != s.active || state.Duration != s.duration ||
+					state.Turns != s.turns || state.Points != s.points || state.Light != s.light {
+					t.Errorf("step %d: got running %v side %d %v turns %v points %v %v, want running %v side %d %v turns %v points %v %v", i,
+						state.Running, state.Active, state.Duration, state.Turns, state.Points, state.Light,
+						s.running, s.active, s.duration, s.turns, s.points, s.light)
+				}
+				if !reflect.DeepEqual(signals, s.signals) {
+					t.Errorf("step %d: got signals %v, want %v", i, signals, s.signals)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package engine
+
+import "time"
+
+// signalShift moves signals against the transitions that trigger them to
+// make up for the latency of the sound output. With a positive offset the
+// signals of the transition at the deadline of a stage are triggered that
+// much earlier and dropped when the transition follows; signals caused by
+// commands cannot be anticipated and are triggered at once. A negative
+// offset delays every signal.
+type signalShift struct {
+	offset  time.Duration
+	armed   time.Time
+	early   []Signal
+	delayed []delayedSignal
+}
+
+type delayedSignal struct {
+	at     time.Time
+	signal Signal
+}
+
+func (s *signalShift) set(offset time.Duration) {
+	s.offset = offset
+	s.early = nil
+}
+
+// trigger reports whether a signal of a transition at now is due at once.
+func (s *signalShift) trigger(signal Signal, now time.Time) bool {
+	if len(s.early) > 0 && s.early[0] == signal {
+		s.early = s.early[1:]
+		return false
+	}
+	if s.offset < 0 {
+		s.delayed = append(s.delayed, delayedSignal{at: now.Add(-s.offset), signal: signal})
+		return false
+	}
+	return true
+}
+
+// due returns the delayed signals whose time has come.
+func (s *signalShift) due(now time.Time) []Signal {
+	var signals []Signal
+	for len(s.delayed) > 0 && !s.delayed[0].at.After(now) {
+		signals = append(signals, s.delayed[0].signal)
+		s.delayed = s.delayed[1:]
+	}
+	return signals
+}
+
+// anticipate returns the signals of the transition at deadline, listed by
+// upcoming, once the deadline is less than the offset ahead. Signals
+// anticipated for an earlier deadline whose transition did not follow are
+// discarded.
+func (s *signalShift) anticipate(deadline, now time.Time, upcoming func() []Signal) []Signal {
+	if s.offset <= 0 {
+		return nil
+	}
+	if !s.armed.Equal(deadline) {
+		s.early = nil
+	} else if len(s.early) > 0 {
+		return nil
+	}
+	if deadline.Sub(now) > s.offset || s.armed.Equal(deadline) {
+		return nil
+	}
+	s.armed = deadline
+	s.early = upcoming()
+	return s.early
+}
+
+// move follows a deadline that has been moved, e.g. by a pause, so that the
+// signals already anticipated for it are not anticipated again.
+func (s *signalShift) move(from, to time.Time) {
+	if len(s.early) > 0 && s.armed.Equal(from) {
+		s.armed = to
+	}
+}
+
+func (s *signalShift) reset() {
+	s.early = nil
+	s.delayed = nil
+}
@@ -0,0 +1,81 @@
+package engine
+
+import "fmt"
+
+// Distance is a block of ends shot at the same distance. Practice ends are
+// shot first. Break is the pause in seconds after the last end.
+type Distance struct {
+	Name         string `json:"name"`
+	PracticeEnds int    `json:"practiceEnds"`
+	Ends         int    `json:"ends"`
+	Break        int    `json:"break"`
+}
+
+type Session struct {
+	Name      string     `json:"name"`
+	Distances []Distance `json:"distances"`
+}
+
+// Schedule is the sequence of sessions of a tournament. An empty schedule
+// repeats the rotation endlessly.
+type Schedule []Session
+
+// Validate reports sessions without distances and distances without ends,
+// which the engine cannot run.
+func (s Schedule) Validate() error {
+	for _, session := range s {
+		if len(session.Distances) == 0 {
+			return fmt.Errorf("session %q without distances", session.Name)
+		}
+		for _, d := range session.Distances {
+			if d.PracticeEnds < 0 || d.Ends < 0 || d.PracticeEnds+d.Ends == 0 {
+				return fmt.Errorf("distance %q without ends", d.Name)
+			}
+		}
+	}
+	return nil
+}
+
+type position struct {
+	session  int
+	distance int
+	end      int
+}
+
+// contains reports whether p is an end of the schedule. The empty schedule
+// only contains the zero position.
+func (s Schedule) contains(p position) bool {
+	if len(s) == 0 {
+		return p == position{}
+	}
+	if p.session < 0 || p.session >= len(s) || p.distance < 0 || p.distance >= len(s[p.session].Distances) {
+		return false
+	}
+	d := s.distance(p)
+	return p.end >= 0 && p.end < d.PracticeEnds+d.Ends
+}
+
+func (s Schedule) distance(p position) Distance {
+	return s[p.session].Distances[p.distance]
+}
+
+// next returns the position of the end following p. The break is the one
+// of the distance that has just been completed; last reports that p was
+// the final end of the schedule.
+func (s Schedule) next(p position) (next position, pause int, last bool) {
+	d := s.distance(p)
+	p.end++
+	if p.end < d.PracticeEnds+d.Ends {
+		return p, 0, false
+	}
+	p.end = 0
+	p.distance++
+	if p.distance >= len(s[p.session].Distances) {
+		p.distance = 0
+		p.session++
+	}
+	if p.session >= len(s) {
+		return p, 0, true
+	}
+	return p, d.Break, false
+}
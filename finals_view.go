@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"drazil/tournament/engine"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// finalsView shows the two clocks of an alternate shooting match. While a
+// shoot-off is set it is shown instead of the final match; once its arrows
+// are shot the operator marks the winner with [1] or [2].
+type finalsView struct {
+	shootOff *engine.Match
+	decided  bool
+	winner   engine.Side
+}
+
+var finals finalsView
+
+func (f *finalsView) current() *engine.Match {
+	if f.shootOff != nil {
+		return f.shootOff
+	}
+	return match
+}
+
+func (f *finalsView) update() {
+	m := f.current()
+	points := 1
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		points = -1
+	}
+	if inpututil.IsKeyJustReleased(ebiten.KeyLeft) {
+		f.decided = false
+		handleMatchEvents(m.Start(engine.Left))
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyRight) {
+		f.decided = false
+		handleMatchEvents(m.Start(engine.Right))
+	} else if inpututil.IsKeyJustReleased(ebiten.KeySpace) {
+		handleMatchEvents(m.Pass())
+	} else if inpututil.IsKeyJustReleased(ebiten.Key1) && f.shootOff != nil {
+		f.decide(engine.Left)
+	} else if inpututil.IsKeyJustReleased(ebiten.Key2) && f.shootOff != nil {
+		f.decide(engine.Right)
+	} else if inpututil.IsKeyJustReleased(ebiten.Key1) {
+		handleMatchEvents(m.AddPoints(engine.Left, points))
+	} else if inpututil.IsKeyJustReleased(ebiten.Key2) {
+		handleMatchEvents(m.AddPoints(engine.Right, points))
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyM) && !m.State().Running && f.shootOff == nil {
+		if m.Config().Mode == engine.Individual {
+			handleMatchEvents(m.SetConfig(engine.TeamFinals()))
+		} else {
+			handleMatchEvents(m.SetConfig(engine.IndividualFinals()))
+		}
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyN) {
+		f.decided = false
+		handleMatchEvents(m.Reset())
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEscape) {
+		if m.State().Running {
+			handleMatchEvents(m.Stop())
+		} else {
+			f.shootOff = nil
+			f.decided = false
+			view = TournamentView
+		}
+	}
+	handleMatchEvents(m.Tick())
+}
+
+// deciding reports whether the arrows of a shoot-off have been shot.
+func (f *finalsView) deciding() bool {
+	if f.shootOff == nil {
+		return false
+	}
+	state := f.shootOff.State()
+	return state.Set > 0 && !state.Running
+}
+
+func (f *finalsView) decide(side engine.Side) {
+	if !f.deciding() {
+		return
+	}
+	f.decided = true
+	f.winner = side
+	if side == engine.Left {
+		log.Printf("shoot-off won by the left side")
+	} else {
+		log.Printf("shoot-off won by the right side")
+	}
+}
+
+func handleMatchEvents(events []engine.MatchEvent) {
+	for _, e := range events {
+		if e.Type == engine.SignalTriggered {
+			playSignal(e.Signal)
+		}
+	}
+}
+
+func (f *finalsView) draw(screen *ebiten.Image) {
+	state := f.current().State()
+	mode := "EINZEL"
+	if state.Mode == engine.Team {
+		mode = "TEAM"
+	}
+	title := fmt.Sprintf("FINALE %s  SET %d", mode, state.Set)
+	if f.shootOff != nil {
+		title = "STECHEN " + mode
+	}
+	text.Draw(screen, title, roundFont, 20, 40, colorWhite)
+	for _, side := range []engine.Side{engine.Left, engine.Right} {
+		x := 20 + int(side)*512
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(9.44, 2)
+		op.GeoM.Translate(float64(x), 60)
+		screen.DrawImage(lightImage(state.Light[side]), op)
+
+		c := colorWhite
+		if state.Running && state.Active == side {
+			c = colorYellow
+			if state.Light[side] == engine.Yellow {
+				c = colorRed
+			}
+		}
+		text.Draw(screen, zero, mediumFont, x, 420, colorDarkGray)
+		text.Draw(screen, fmt.Sprintf("%3d", state.Duration[side]), mediumFont, x, 420, c)
+		text.Draw(screen, fmt.Sprintf("TURNS:%d", state.Turns[side]), roundFont, x, 480, colorWhite)
+		if f.shootOff != nil {
+			if f.decided && f.winner == side {
+				text.Draw(screen, "WINNER", roundFont, x, 600, colorGreen)
+			}
+			continue
+		}
+		text.Draw(screen, "SET POINTS", roundFont, x, 540, colorWhite)
+		text.Draw(screen, fmt.Sprintf("%d", state.Points[side]), mediumFont, x, 750, colorWhite)
+	}
+	if f.deciding() && !f.decided {
+		text.Draw(screen, "CLOSEST TO CENTRE DECIDES", infoFontLarge, 20, 680, colorYellow)
+		text.Draw(screen, "Sieger: [1] links  [2] rechts", infoFontSmall, 20, 710, colorWhite)
+	}
+}
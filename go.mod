@@ -3,15 +3,18 @@ module drazil/tournament
 go 1.17
 
 require (
-	github.com/hajimehoshi/ebiten v1.12.12
 	github.com/hajimehoshi/ebiten/v2 v2.2.1
+	github.com/tarm/serial v0.0.0-20180830185346-98f6abe2eb07
 	golang.org/x/image v0.0.0-20211028202545-6944b10bf410
 )
 
 require (
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20211024062804-40e447a793be // indirect
+	github.com/hajimehoshi/go-mp3 v0.3.2 // indirect
 	github.com/hajimehoshi/oto/v2 v2.1.0-alpha.3 // indirect
 	github.com/jezek/xgb v0.0.0-20210312150743-0e0f116e1240 // indirect
+	github.com/jfreymuth/oggvorbis v1.0.3 // indirect
+	github.com/jfreymuth/vorbis v1.0.2 // indirect
 	golang.org/x/exp v0.0.0-20211029182501-9b944d235b9d // indirect
 	golang.org/x/mobile v0.0.0-20211102000317-2ab7fee9df46 // indirect
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
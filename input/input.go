@@ -0,0 +1,35 @@
+package input
+
+import (
+	"fmt"
+
+	"drazil/tournament/control"
+)
+
+// Source is a command device besides the keyboard. Poll is called from
+// the game loop and returns the commands received since the last call
+// without blocking.
+type Source interface {
+	Name() string
+	Poll() []control.Command
+}
+
+// Source types of a Config.
+const TypeSerial = "serial"
+
+// Config selects and configures one source in the configuration file.
+type Config struct {
+	Type   string        `json:"type"`
+	Serial *SerialConfig `json:"serial,omitempty"`
+}
+
+func Open(config Config) (Source, error) {
+	switch config.Type {
+	case TypeSerial:
+		if config.Serial == nil {
+			return nil, fmt.Errorf("input: serial source without serial settings")
+		}
+		return NewSerial(*config.Serial)
+	}
+	return nil, fmt.Errorf("input: unknown source %q", config.Type)
+}
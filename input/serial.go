@@ -0,0 +1,157 @@
+package input
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"drazil/tournament/control"
+
+	"github.com/tarm/serial"
+)
+
+// SerialConfig describes a keypad that sends characters or lines over a
+// serial port, e.g. a wireless keypad with a USB dongle. The port is read
+// in raw mode with Baud (default 9600) and 8N1, so every character arrives
+// as soon as it is sent. Keys maps the messages to commands; no message may
+// be the beginning of another. A repeated command within Debounce
+// milliseconds is ignored.
+type SerialConfig struct {
+	Port     string                     `json:"port"`
+	Baud     int                        `json:"baud,omitempty"`
+	Keys     map[string]control.Command `json:"keys"`
+	Debounce int                        `json:"debounce"`
+}
+
+// Serial reads a keypad in the background. A failing port, e.g. an
+// unplugged dongle, is opened again every few seconds.
+type Serial struct {
+	config   SerialConfig
+	debounce time.Duration
+	commands chan control.Command
+	last     map[control.Command]time.Time
+}
+
+func NewSerial(config SerialConfig) (*Serial, error) {
+	if len(config.Keys) == 0 {
+		return nil, fmt.Errorf("input: %s: no keys defined", config.Port)
+	}
+	for message, c := range config.Keys {
+		switch c {
+		case control.Start, control.Cancel, control.Pause, control.Resume, control.Restart, control.Emergency:
+		default:
+			return nil, fmt.Errorf("input: %s: key %q: unknown command %q", config.Port, message, c)
+		}
+	}
+	port, err := open(config)
+	if err != nil {
+		return nil, err
+	}
+	debounce := 300 * time.Millisecond
+	if config.Debounce > 0 {
+		debounce = time.Duration(config.Debounce) * time.Millisecond
+	}
+	s := &Serial{
+		config:   config,
+		debounce: debounce,
+		commands: make(chan control.Command, 16),
+		last:     map[control.Command]time.Time{},
+	}
+	go s.run(port)
+	return s, nil
+}
+
+func (s *Serial) Name() string {
+	return s.config.Port
+}
+
+func (s *Serial) Poll() []control.Command {
+	var commands []control.Command
+	for {
+		select {
+		case c := <-s.commands:
+			commands = append(commands, c)
+		default:
+			return commands
+		}
+	}
+}
+
+func open(config SerialConfig) (*serial.Port, error) {
+	baud := config.Baud
+	if baud == 0 {
+		baud = 9600
+	}
+	return serial.OpenPort(&serial.Config{Name: config.Port, Baud: baud})
+}
+
+func (s *Serial) run(port *serial.Port) {
+	for {
+		err := s.read(port)
+		port.Close()
+		log.Printf("input: %s: %v", s.config.Port, err)
+		for {
+			time.Sleep(3 * time.Second)
+			if port, err = open(s.config); err == nil {
+				log.Printf("input: %s: reopened", s.config.Port)
+				break
+			}
+		}
+	}
+}
+
+// read collects characters until they form a message of the mapping.
+// Characters that cannot start a message and line breaks discard what has
+// been collected.
+func (s *Serial) read(port io.Reader) error {
+	buf := make([]byte, 64)
+	var message []byte
+	for {
+		n, err := port.Read(buf)
+		if err != nil {
+			return err
+		}
+		for _, b := range buf[:n] {
+			if b == '\r' || b == '\n' {
+				if len(message) > 0 {
+					log.Printf("input: %s: unknown message %q", s.config.Port, message)
+				}
+				message = message[:0]
+				continue
+			}
+			message = append(message, b)
+			if c, ok := s.config.Keys[string(message)]; ok {
+				s.send(c)
+				message = message[:0]
+			} else if !s.prefix(string(message)) {
+				log.Printf("input: %s: unknown message %q", s.config.Port, message)
+				message = message[:0]
+			}
+		}
+	}
+}
+
+func (s *Serial) prefix(message string) bool {
+	for key := range s.config.Keys {
+		if strings.HasPrefix(key, message) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Serial) send(c control.Command) {
+	now := time.Now()
+	if now.Sub(s.last[c]) < s.debounce {
+		log.Printf("input: %s: %s ignored, repeated within %s", s.config.Port, c, s.debounce)
+		return
+	}
+	s.last[c] = now
+	select {
+	case s.commands <- c:
+	default:
+		log.Printf("input: %s: %s dropped, timer busy", s.config.Port, c)
+	}
+}
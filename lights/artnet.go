@@ -0,0 +1,121 @@
+package lights
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"drazil/tournament/engine"
+)
+
+const ArtNetPort = 6454
+
+// Channels maps DMX channels (1-512) to their values.
+type Channels map[int]byte
+
+// ArtNetConfig maps the stage and the light colour, by their names, to
+// DMX channel values on a universe. Channels of the light override those
+// of the stage; all other channels are 0. Address is a node or broadcast
+// address, the port defaults to 6454.
+type ArtNetConfig struct {
+	Address  string              `json:"address"`
+	Universe int                 `json:"universe"`
+	Stages   map[string]Channels `json:"stages,omitempty"`
+	Lights   map[string]Channels `json:"lights,omitempty"`
+}
+
+// ArtNet sends the DMX frame of the current state on every transition and
+// repeats it every second, as many nodes switch off without refresh.
+type ArtNet struct {
+	config ArtNetConfig
+	conn   *net.UDPConn
+	mu     sync.Mutex
+	frame  [512]byte
+	seq    byte
+	done   chan struct{}
+}
+
+func NewArtNet(config ArtNetConfig) (*ArtNet, error) {
+	if config.Universe < 0 || config.Universe > 0x7fff {
+		return nil, fmt.Errorf("lights: invalid universe %d", config.Universe)
+	}
+	for _, mapping := range []map[string]Channels{config.Stages, config.Lights} {
+		for name, channels := range mapping {
+			for c := range channels {
+				if c < 1 || c > 512 {
+					return nil, fmt.Errorf("lights: %s: invalid DMX channel %d", name, c)
+				}
+			}
+		}
+	}
+	address := config.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, strconv.Itoa(ArtNetPort))
+	}
+	addr, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp4", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	a := &ArtNet{config: config, conn: conn, done: make(chan struct{})}
+	go a.refresh()
+	return a, nil
+}
+
+func (a *ArtNet) Update(state engine.State) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.frame = [512]byte{}
+	for c, v := range a.config.Stages[state.Stage.String()] {
+		a.frame[c-1] = v
+	}
+	for c, v := range a.config.Lights[state.Light.String()] {
+		a.frame[c-1] = v
+	}
+	return a.send()
+}
+
+func (a *ArtNet) Close() error {
+	close(a.done)
+	return a.conn.Close()
+}
+
+func (a *ArtNet) refresh() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.mu.Lock()
+			a.send()
+			a.mu.Unlock()
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// send writes an ArtDmx packet with the whole frame.
+func (a *ArtNet) send() error {
+	a.seq++
+	if a.seq == 0 {
+		a.seq = 1
+	}
+	length := len(a.frame)
+	packet := make([]byte, 0, 18+length)
+	packet = append(packet, "Art-Net\x00"...)
+	packet = append(packet,
+		0x00, 0x50, // OpDmx, little endian
+		0, 14, // protocol version
+		a.seq, 0,
+		byte(a.config.Universe), byte(a.config.Universe>>8),
+		byte(length>>8), byte(length))
+	packet = append(packet, a.frame[:]...)
+	_, err := a.conn.Write(packet)
+	return err
+}
@@ -0,0 +1,71 @@
+package lights
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"drazil/tournament/engine"
+)
+
+func TestArtNet(t *testing.T) {
+	listener, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	a, err := NewArtNet(ArtNetConfig{
+		Address:  listener.LocalAddr().String(),
+		Universe: 0x0102,
+		Stages:   map[string]Channels{"action": {1: 255, 2: 128}},
+		Lights:   map[string]Channels{"green": {2: 0, 3: 200}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if err := a.Update(engine.State{Stage: engine.Action, Light: engine.Green}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packet := buf[:n]
+	if n != 18+512 {
+		t.Fatalf("got %d bytes, want %d", n, 18+512)
+	}
+	if !bytes.Equal(packet[:8], []byte("Art-Net\x00")) {
+		t.Errorf("got header %q", packet[:8])
+	}
+	if packet[8] != 0x00 || packet[9] != 0x50 {
+		t.Errorf("got OpCode %#02x%02x, want OpDmx 0x5000 little endian", packet[9], packet[8])
+	}
+	if packet[10] != 0 || packet[11] != 14 {
+		t.Errorf("got protocol version %d.%d, want 0.14", packet[10], packet[11])
+	}
+	if packet[12] == 0 {
+		t.Error("got sequence 0, want a running sequence")
+	}
+	if packet[14] != 0x02 || packet[15] != 0x01 {
+		t.Errorf("got universe bytes %#x %#x, want 0x2 0x1", packet[14], packet[15])
+	}
+	if length := int(packet[16])<<8 | int(packet[17]); length != 512 {
+		t.Errorf("got length %d, want 512", length)
+	}
+	data := packet[18:]
+	if data[0] != 255 || data[1] != 0 || data[2] != 200 {
+		t.Errorf("got channels 1-3 %v, want [255 0 200]", data[:3])
+	}
+	for c, v := range data[3:] {
+		if v != 0 {
+			t.Errorf("got channel %d at %d, want 0", c+4, v)
+			break
+		}
+	}
+}
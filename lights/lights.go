@@ -0,0 +1,65 @@
+package lights
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"drazil/tournament/engine"
+)
+
+// Driver switches physical lamps. Update is called with the timer state
+// after every change of the signal light or stage.
+type Driver interface {
+	Update(state engine.State) error
+	Close() error
+}
+
+// Driver types of a Config.
+const (
+	TypeSerial    = "serial"
+	TypeArtNet    = "artnet"
+	TypeSimulated = "simulated"
+)
+
+// Config selects and configures one driver in the configuration file.
+type Config struct {
+	Type   string        `json:"type"`
+	Serial *SerialConfig `json:"serial,omitempty"`
+	ArtNet *ArtNetConfig `json:"artnet,omitempty"`
+}
+
+func Open(config Config) (Driver, error) {
+	switch config.Type {
+	case TypeSerial:
+		if config.Serial == nil {
+			return nil, fmt.Errorf("lights: serial driver without serial settings")
+		}
+		return NewSerial(*config.Serial)
+	case TypeArtNet:
+		if config.ArtNet == nil {
+			return nil, fmt.Errorf("lights: artnet driver without artnet settings")
+		}
+		return NewArtNet(*config.ArtNet)
+	case TypeSimulated:
+		return NewSimulated(), nil
+	}
+	return nil, fmt.Errorf("lights: unknown driver %q", config.Type)
+}
+
+// Bytes is a command sequence, written as hex in the configuration file,
+// e.g. "A0 01 01 A2".
+type Bytes []byte
+
+func (b Bytes) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("% X", []byte(b))), nil
+}
+
+func (b *Bytes) UnmarshalText(text []byte) error {
+	data, err := hex.DecodeString(strings.Join(strings.Fields(string(text)), ""))
+	if err != nil {
+		return fmt.Errorf("lights: invalid command %q: %w", text, err)
+	}
+	*b = data
+	return nil
+}
@@ -0,0 +1,97 @@
+package lights
+
+import (
+	"log"
+
+	"drazil/tournament/engine"
+
+	"github.com/tarm/serial"
+)
+
+// Lamp holds the commands that switch one relay of a board.
+type Lamp struct {
+	On  Bytes `json:"on"`
+	Off Bytes `json:"off"`
+}
+
+// SerialConfig describes a USB-serial relay board with one relay per lamp.
+// Port is the device, e.g. /dev/ttyUSB0 or COM3, opened in raw mode with
+// Baud (default 9600) and 8N1.
+type SerialConfig struct {
+	Port   string `json:"port"`
+	Baud   int    `json:"baud,omitempty"`
+	Red    Lamp   `json:"red"`
+	Yellow Lamp   `json:"yellow"`
+	Green  Lamp   `json:"green"`
+}
+
+// Serial drives a relay board. Commands are written in the background so
+// that a slow port does not hold up the timer; a light that has not been
+// written yet is replaced by the next one.
+type Serial struct {
+	config SerialConfig
+	port   *serial.Port
+	light  engine.Light
+	lights chan engine.Light
+	done   chan struct{}
+}
+
+func NewSerial(config SerialConfig) (*Serial, error) {
+	baud := config.Baud
+	if baud == 0 {
+		baud = 9600
+	}
+	port, err := serial.OpenPort(&serial.Config{Name: config.Port, Baud: baud})
+	if err != nil {
+		return nil, err
+	}
+	s := &Serial{config: config, port: port, light: -1, lights: make(chan engine.Light, 1), done: make(chan struct{})}
+	go s.run()
+	return s, nil
+}
+
+func (s *Serial) Update(state engine.State) error {
+	if state.Light == s.light {
+		return nil
+	}
+	s.light = state.Light
+	select {
+	case <-s.lights:
+	default:
+	}
+	s.lights <- state.Light
+	return nil
+}
+
+func (s *Serial) Close() error {
+	close(s.lights)
+	<-s.done
+	return s.port.Close()
+}
+
+func (s *Serial) run() {
+	defer close(s.done)
+	for light := range s.lights {
+		if err := s.write(light); err != nil {
+			log.Printf("lights: %s: %v", s.config.Port, err)
+		}
+	}
+}
+
+// write switches the other lamps off before the new one is switched on,
+// so that two lamps are never lit together.
+func (s *Serial) write(light engine.Light) error {
+	lamps := map[engine.Light]Lamp{engine.Red: s.config.Red, engine.Yellow: s.config.Yellow, engine.Green: s.config.Green}
+	for l, lamp := range lamps {
+		if l != light {
+			if _, err := s.port.Write(lamp.Off); err != nil {
+				return err
+			}
+		}
+	}
+	if lamp, ok := lamps[light]; ok {
+		_, err := s.port.Write(lamp.On)
+		return err
+	}
+	return nil
+}
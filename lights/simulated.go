@@ -0,0 +1,39 @@
+package lights
+
+import (
+	"log"
+	"sync"
+
+	"drazil/tournament/engine"
+)
+
+// Simulated stands in for lamp hardware; it logs every change and keeps
+// the current light.
+type Simulated struct {
+	mu    sync.Mutex
+	light engine.Light
+}
+
+func NewSimulated() *Simulated {
+	return &Simulated{light: engine.Off}
+}
+
+func (s *Simulated) Update(state engine.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.light != state.Light {
+		log.Printf("lights: %s", state.Light)
+	}
+	s.light = state.Light
+	return nil
+}
+
+func (s *Simulated) Light() engine.Light {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.light
+}
+
+func (s *Simulated) Close() error {
+	return nil
+}
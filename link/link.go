@@ -0,0 +1,157 @@
+package link
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"drazil/tournament/engine"
+)
+
+const DefaultGroup = "239.17.43.1:17430"
+
+// Packet carries the engine state of the master. Event is set when the
+// packet reports a transition; heartbeats only carry the snapshot. Time is
+// the clock of the master in Unix milliseconds.
+type Packet struct {
+	Node     string          `json:"node"`
+	Seq      uint64          `json:"seq"`
+	Time     int64           `json:"time"`
+	Event    *engine.Event   `json:"event,omitempty"`
+	Snapshot engine.Snapshot `json:"snapshot"`
+}
+
+// NodeName identifies this instance in packets.
+func NodeName() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Sender multicasts packets of the master.
+type Sender struct {
+	mu   sync.Mutex
+	conn *net.UDPConn
+	node string
+	seq  uint64
+}
+
+func NewSender(group, node string) (*Sender, error) {
+	addr, err := net.ResolveUDPAddr("udp4", group)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp4", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Sender{conn: conn, node: node}, nil
+}
+
+func (s *Sender) Send(p Packet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	p.Node = s.node
+	p.Seq = s.seq
+	p.Time = time.Now().UnixNano() / int64(time.Millisecond)
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.Write(data)
+	return err
+}
+
+// Heartbeat sends the snapshot returned by snapshot every interval.
+func (s *Sender) Heartbeat(interval time.Duration, snapshot func() engine.Snapshot) {
+	for range time.Tick(interval) {
+		if err := s.Send(Packet{Snapshot: snapshot()}); err != nil {
+			log.Printf("link: %v", err)
+		}
+	}
+}
+
+// Receiver listens for packets of a master on the multicast group.
+type Receiver struct {
+	conn    *net.UDPConn
+	packets chan Packet
+	mu      sync.Mutex
+	last    time.Time
+	master  net.IP
+	closed  bool
+}
+
+func NewReceiver(group string) (*Receiver, error) {
+	addr, err := net.ResolveUDPAddr("udp4", group)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Receiver{conn: conn, packets: make(chan Packet, 64)}, nil
+}
+
+func (r *Receiver) Packets() <-chan Packet {
+	return r.packets
+}
+
+// Run reads packets until the connection fails. Packets arriving out of
+// order are dropped.
+func (r *Receiver) Run() error {
+	buf := make([]byte, 64*1024)
+	seq := map[string]uint64{}
+	for {
+		n, addr, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if r.closed {
+				return nil
+			}
+			return err
+		}
+		var p Packet
+		if err := json.Unmarshal(buf[:n], &p); err != nil {
+			log.Printf("link: %v", err)
+			continue
+		}
+		if p.Seq <= seq[p.Node] {
+			continue
+		}
+		seq[p.Node] = p.Seq
+		r.mu.Lock()
+		r.last = time.Now()
+		r.master = addr.IP
+		r.mu.Unlock()
+		r.packets <- p
+	}
+}
+
+// Lost reports whether no packet has arrived within timeout.
+func (r *Receiver) Lost(timeout time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Since(r.last) > timeout
+}
+
+// Master returns the address of the last master heard or nil.
+func (r *Receiver) Master() net.IP {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.master
+}
+
+// Close stops receiving; Run returns nil.
+func (r *Receiver) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.master = nil
+	r.mu.Unlock()
+	return r.conn.Close()
+}
@@ -0,0 +1,186 @@
+package link
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const DefaultSyncPort = 17431
+
+// syncMessage is one NTP style exchange: T1 is the send time of the
+// follower, T2 and T3 the receive and send time of the master. Offset and
+// RTT report the last estimate of the follower for diagnostics.
+type syncMessage struct {
+	Node   string        `json:"node"`
+	T1     int64         `json:"t1"`
+	T2     int64         `json:"t2"`
+	T3     int64         `json:"t3"`
+	Offset time.Duration `json:"offset"`
+	RTT    time.Duration `json:"rtt"`
+}
+
+// NodeSync is the clock offset of a node to the master; a positive offset
+// means the master clock is ahead.
+type NodeSync struct {
+	Node   string
+	Offset time.Duration
+	RTT    time.Duration
+	Seen   time.Time
+}
+
+// TimeServer answers the time requests of followers on the master.
+type TimeServer struct {
+	conn  *net.UDPConn
+	mu    sync.Mutex
+	nodes map[string]NodeSync
+}
+
+func NewTimeServer(port int) (*TimeServer, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	if err != nil {
+		return nil, err
+	}
+	return &TimeServer{conn: conn, nodes: map[string]NodeSync{}}, nil
+}
+
+func (s *TimeServer) Run() error {
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return err
+		}
+		received := time.Now().UnixNano()
+		var m syncMessage
+		if json.Unmarshal(buf[:n], &m) != nil {
+			continue
+		}
+		if m.RTT > 0 {
+			s.mu.Lock()
+			s.nodes[m.Node] = NodeSync{Node: m.Node, Offset: m.Offset, RTT: m.RTT, Seen: time.Now()}
+			s.mu.Unlock()
+		}
+		m.T2 = received
+		m.T3 = time.Now().UnixNano()
+		data, _ := json.Marshal(m)
+		s.conn.WriteToUDP(data, addr)
+	}
+}
+
+// Nodes returns the followers heard from within the last ten seconds.
+func (s *TimeServer) Nodes() []NodeSync {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var nodes []NodeSync
+	for _, n := range s.nodes {
+		if time.Since(n.Seen) < 10*time.Second {
+			nodes = append(nodes, n)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+	return nodes
+}
+
+type sample struct {
+	offset time.Duration
+	rtt    time.Duration
+}
+
+// TimeClient estimates the offset of the local clock to the master. Of the
+// recent samples the one with the shortest round trip is used, as it is
+// the least disturbed by network delay.
+type TimeClient struct {
+	node    string
+	mu      sync.Mutex
+	samples []sample
+	best    sample
+	valid   bool
+}
+
+func NewTimeClient(node string) *TimeClient {
+	return &TimeClient{node: node}
+}
+
+// Offset returns the estimated offset and round trip time; ok is false
+// before the first exchange has succeeded.
+func (c *TimeClient) Offset() (offset, rtt time.Duration, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.best.offset, c.best.rtt, c.valid
+}
+
+// Run exchanges time with the master every interval until stop is closed.
+// master returns the address of the master or nil while it is unknown.
+func (c *TimeClient) Run(master func() net.IP, port int, interval time.Duration, stop <-chan struct{}) error {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	buf := make([]byte, 1024)
+	var last net.IP
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+		ip := master()
+		if ip == nil {
+			continue
+		}
+		if !ip.Equal(last) {
+			c.reset()
+			last = ip
+		}
+		offset, rtt, _ := c.Offset()
+		request, _ := json.Marshal(syncMessage{Node: c.node, T1: time.Now().UnixNano(), Offset: offset, RTT: rtt})
+		addr := &net.UDPAddr{IP: ip, Port: port}
+		if _, err := conn.WriteToUDP(request, addr); err != nil {
+			continue
+		}
+		conn.SetReadDeadline(time.Now().Add(interval))
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			continue
+		}
+		t4 := time.Now().UnixNano()
+		var m syncMessage
+		if json.Unmarshal(buf[:n], &m) != nil {
+			continue
+		}
+		c.add(sample{
+			offset: time.Duration(((m.T2 - m.T1) + (m.T3 - t4)) / 2),
+			rtt:    time.Duration((t4 - m.T1) - (m.T3 - m.T2)),
+		})
+	}
+}
+
+// reset drops the samples of a previous master.
+func (c *TimeClient) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples = nil
+	c.valid = false
+}
+
+func (c *TimeClient) add(s sample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples = append(c.samples, s)
+	if len(c.samples) > 8 {
+		c.samples = c.samples[1:]
+	}
+	c.best = c.samples[0]
+	for _, s := range c.samples[1:] {
+		if s.rtt < c.best.rtt {
+			c.best = s
+		}
+	}
+	c.valid = true
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// makeUpForm collects the parameters of a make-up end before it is
+// started from the tournament view.
+type makeUpForm struct {
+	selected int
+	arrows   int
+	seconds  int
+	targets  string
+}
+
+var makeUp = makeUpForm{arrows: 1, seconds: 40}
+
+func (m *makeUpForm) open() {
+	m.selected = 0
+	m.targets = ""
+	view = MakeUpView
+}
+
+func (m *makeUpForm) update() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) || inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		m.selected = 1 - m.selected
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
+		m.change(-1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
+		m.change(1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(m.targets) > 0 {
+		m.targets = m.targets[:len(m.targets)-1]
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEnter) {
+		view = TournamentView
+		handleEvents(timer.MakeUp(m.arrows, m.seconds, strings.TrimSpace(m.targets)))
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEscape) {
+		view = TournamentView
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if (r >= '0' && r <= '9') || r == ',' || r == ' ' || r == '-' {
+			m.targets += string(r)
+		}
+	}
+}
+
+func (m *makeUpForm) change(delta int) {
+	if m.selected == 0 {
+		m.arrows = clamp(m.arrows+delta, 1, 6)
+	} else {
+		m.seconds = clamp(m.seconds+delta*5, 5, 120)
+	}
+}
+
+func (m *makeUpForm) draw(screen *ebiten.Image) {
+	text.Draw(screen, "Nachschiessen", infoFontLarge, 200, 50, colorWhite)
+	text.Draw(screen, "[PFEILE] Auswahl/Wert  [0-9] Scheiben  [RETURN] Start  [ESC] Abbrechen", infoFontSmall, 200, 80, colorWhite)
+	for i, line := range []string{fmt.Sprintf("Pfeile: %d", m.arrows), fmt.Sprintf("Sekunden/Pfeil: %d", m.seconds)} {
+		c := colorWhite
+		if i == m.selected {
+			c = colorYellow
+		}
+		text.Draw(screen, line, infoFontLarge, 200, 150+i*45, c)
+	}
+	text.Draw(screen, "Scheiben: "+m.targets+"_", infoFontLarge, 200, 240, colorWhite)
+	text.Draw(screen, fmt.Sprintf("Schiesszeit: %d s", m.arrows*m.seconds), infoFontLarge, 200, 330, colorWhite)
+}
@@ -0,0 +1,213 @@
+package mqtt
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"drazil/tournament/control"
+	"drazil/tournament/engine"
+)
+
+// Config of the connection to a broker, usually one running on the timer
+// machine itself.
+type Config struct {
+	Broker        string `json:"broker"`
+	ClientID      string `json:"clientId"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	StageTopic    string `json:"stageTopic"`
+	DurationTopic string `json:"durationTopic"`
+	LightTopic    string `json:"lightTopic"`
+	CommandTopic  string `json:"commandTopic"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Broker:        "localhost:1883",
+		ClientID:      "tournament-timer",
+		StageTopic:    "tournament/stage",
+		DurationTopic: "tournament/duration",
+		LightTopic:    "tournament/light",
+		CommandTopic:  "tournament/command",
+	}
+}
+
+// commands maps the payloads of the command topic to timer commands.
+var commands = map[string]control.Command{
+	"start":     control.Start,
+	"stop":      control.Cancel,
+	"cancel":    control.Cancel,
+	"emergency": control.Pause,
+	"pause":     control.Pause,
+	"resume":    control.Resume,
+	"restart":   control.Restart,
+}
+
+// Bridge publishes the stage, the seconds left and the light retained, so
+// that devices connecting later get the current values, and passes the
+// commands of the command topic to the timer. A lost connection is
+// reestablished with increasing delay.
+type Bridge struct {
+	config   Config
+	commands *control.Queue
+	mu       sync.Mutex
+	state    engine.State
+	changed  map[string]bool
+	notify   chan struct{}
+}
+
+// NewBridge rejects a password without a username, which MQTT 3.1.1 does
+// not allow.
+func NewBridge(config Config, commands *control.Queue, state engine.State) (*Bridge, error) {
+	if config.Password != "" && config.Username == "" {
+		return nil, errors.New("mqtt: password without username")
+	}
+	return &Bridge{
+		config:   config,
+		commands: commands,
+		state:    state,
+		changed:  map[string]bool{},
+		notify:   make(chan struct{}, 1),
+	}, nil
+}
+
+// Publish queues the values changed by an event; it never blocks.
+func (b *Bridge) Publish(e engine.Event) {
+	b.mu.Lock()
+	b.state = e.State
+	switch e.Type {
+	case engine.StageChanged, engine.PauseChanged:
+		b.changed[b.config.StageTopic] = true
+		b.changed[b.config.DurationTopic] = true
+	case engine.DurationChanged:
+		b.changed[b.config.DurationTopic] = true
+	case engine.LightChanged:
+		b.changed[b.config.LightTopic] = true
+	}
+	b.mu.Unlock()
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run keeps the connection to the broker; it never returns.
+func (b *Bridge) Run() {
+	delay := time.Second
+	for {
+		c, err := dial(b.config)
+		if err != nil {
+			log.Printf("mqtt: %v, retry in %s", err, delay)
+			time.Sleep(delay)
+			if delay < 30*time.Second {
+				delay *= 2
+			}
+			continue
+		}
+		log.Printf("mqtt: connected to %s", b.config.Broker)
+		delay = time.Second
+		err = b.serve(c)
+		c.close()
+		log.Printf("mqtt: connection lost: %v", err)
+	}
+}
+
+func (b *Bridge) serve(c *conn) error {
+	if err := c.subscribe(b.config.CommandTopic); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	for _, topic := range []string{b.config.StageTopic, b.config.DurationTopic, b.config.LightTopic} {
+		b.changed[topic] = true
+	}
+	b.mu.Unlock()
+
+	// Commands are executed one after another in the order they arrive.
+	// Retained commands are stale, e.g. a "start" left on the broker by a
+	// misconfigured device, and are ignored.
+	failed := make(chan error, 1)
+	go func() {
+		for {
+			topic, payload, retained, err := c.message()
+			if err != nil {
+				failed <- err
+				return
+			}
+			if topic != b.config.CommandTopic {
+				continue
+			}
+			if retained {
+				log.Printf("mqtt: retained command %q ignored", payload)
+				continue
+			}
+			b.execute(string(payload))
+		}
+	}()
+
+	ping := time.NewTicker(c.keepAlive / 2)
+	defer ping.Stop()
+	if err := b.flush(c); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-b.notify:
+			if err := b.flush(c); err != nil {
+				return err
+			}
+		case <-ping.C:
+			if err := c.ping(); err != nil {
+				return err
+			}
+		case err := <-failed:
+			return err
+		}
+	}
+}
+
+// flush publishes all changed values.
+func (b *Bridge) flush(c *conn) error {
+	b.mu.Lock()
+	state := b.state
+	changed := b.changed
+	b.changed = map[string]bool{}
+	b.mu.Unlock()
+	stage := state.Stage.String()
+	if state.Paused {
+		stage = "paused"
+	}
+	values := map[string]string{
+		b.config.StageTopic:    stage,
+		b.config.DurationTopic: strconv.Itoa(state.Duration),
+		b.config.LightTopic:    state.Light.String(),
+	}
+	for topic := range changed {
+		if err := c.publish(topic, []byte(values[topic]), true); err != nil {
+			b.mu.Lock()
+			for topic := range changed {
+				b.changed[topic] = true
+			}
+			b.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Bridge) execute(payload string) {
+	command, ok := commands[strings.ToLower(strings.TrimSpace(payload))]
+	if !ok {
+		log.Printf("mqtt: unknown command %q", payload)
+		return
+	}
+	err := b.commands.Send(command)
+	if err != nil {
+		log.Printf("mqtt: %s: %v", command, err)
+	} else {
+		log.Printf("mqtt: %s", command)
+	}
+}
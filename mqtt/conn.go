@@ -0,0 +1,181 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Packet types of MQTT 3.1.1 used by the timer.
+const (
+	typeConnect    = 1
+	typeConnAck    = 2
+	typePublish    = 3
+	typeSubscribe  = 8
+	typePingReq    = 12
+	typeDisconnect = 14
+)
+
+// conn is a minimal MQTT 3.1.1 client connection: QoS 0 only, which is
+// all the timer needs as states are published retained and repeated.
+type conn struct {
+	c         net.Conn
+	r         *bufio.Reader
+	mu        sync.Mutex
+	keepAlive time.Duration
+}
+
+func dial(config Config) (*conn, error) {
+	c, err := net.DialTimeout("tcp", config.Broker, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	mc := &conn{c: c, r: bufio.NewReader(c), keepAlive: 30 * time.Second}
+
+	flags := byte(0x02) // clean session
+	var payload []byte
+	payload = appendString(payload, config.ClientID)
+	if config.Username != "" {
+		flags |= 0x80
+		payload = appendString(payload, config.Username)
+	}
+	if config.Password != "" {
+		flags |= 0x40
+		payload = appendString(payload, config.Password)
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = appendUint16(body, uint16(mc.keepAlive/time.Second))
+	if err := mc.write(typeConnect<<4, append(body, payload...)); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	header, ack, err := mc.read()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if header>>4 != typeConnAck || len(ack) != 2 {
+		c.Close()
+		return nil, errors.New("mqtt: no connection acknowledgement")
+	}
+	if ack[1] != 0 {
+		c.Close()
+		return nil, fmt.Errorf("mqtt: connection refused with code %d", ack[1])
+	}
+	return mc, nil
+}
+
+func (c *conn) publish(topic string, payload []byte, retain bool) error {
+	header := byte(typePublish << 4)
+	if retain {
+		header |= 0x01
+	}
+	return c.write(header, append(appendString(nil, topic), payload...))
+}
+
+func (c *conn) subscribe(topic string) error {
+	body := []byte{0, 1} // packet identifier
+	body = appendString(body, topic)
+	return c.write(typeSubscribe<<4|0x02, append(body, 0))
+}
+
+func (c *conn) ping() error {
+	return c.write(typePingReq<<4, nil)
+}
+
+func (c *conn) close() error {
+	c.write(typeDisconnect<<4, nil)
+	return c.c.Close()
+}
+
+// message reads packets until a publish arrives and returns its topic,
+// payload and retain flag. The connection counts as lost when nothing, not
+// even a ping response, arrives within one and a half keep-alive periods.
+func (c *conn) message() (string, []byte, bool, error) {
+	for {
+		c.c.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		header, body, err := c.read()
+		if err != nil {
+			return "", nil, false, err
+		}
+		if header>>4 != typePublish {
+			continue
+		}
+		if len(body) < 2 {
+			return "", nil, false, errors.New("mqtt: short publish packet")
+		}
+		n := int(binary.BigEndian.Uint16(body))
+		if len(body) < 2+n {
+			return "", nil, false, errors.New("mqtt: short publish packet")
+		}
+		topic, payload := string(body[2:2+n]), body[2+n:]
+		if qos := header >> 1 & 0x03; qos > 0 && len(payload) >= 2 {
+			payload = payload[2:] // packet identifier
+		}
+		return topic, payload, header&0x01 != 0, nil
+	}
+}
+
+func (c *conn) write(header byte, body []byte) error {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	packet = append(packet, body...)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.c.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := c.c.Write(packet)
+	return err
+}
+
+func (c *conn) read() (byte, []byte, error) {
+	header, err := c.r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, shift := 0, 0
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+		if shift > 21 {
+			return 0, nil, errors.New("mqtt: invalid packet length")
+		}
+	}
+	body := make([]byte, n)
+	_, err = io.ReadFull(c.r, body)
+	return header, body, err
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
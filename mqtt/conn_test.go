@@ -0,0 +1,113 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"drazil/tournament/control"
+	"drazil/tournament/engine"
+)
+
+func pipe() (*conn, *conn) {
+	a, b := net.Pipe()
+	return &conn{c: a, r: bufio.NewReader(a), keepAlive: time.Second},
+		&conn{c: b, r: bufio.NewReader(b), keepAlive: time.Second}
+}
+
+func TestPublishRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		topic   string
+		payload []byte
+		retain  bool
+	}{
+		{name: "empty", topic: "tournament/command"},
+		{name: "retained", topic: "tournament/stage", payload: []byte("action"), retain: true},
+		{name: "two length bytes", topic: "t", payload: bytes.Repeat([]byte{'x'}, 200)},
+		{name: "three length bytes", topic: "t", payload: bytes.Repeat([]byte{'y'}, 20000)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, broker := pipe()
+			defer client.c.Close()
+			defer broker.c.Close()
+			failed := make(chan error, 1)
+			go func() {
+				failed <- client.publish(test.topic, test.payload, test.retain)
+			}()
+			topic, payload, retained, err := broker.message()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := <-failed; err != nil {
+				t.Fatal(err)
+			}
+			if topic != test.topic || !bytes.Equal(payload, test.payload) || retained != test.retain {
+				t.Errorf("got %q %d bytes retained %v, want %q %d bytes retained %v",
+					topic, len(payload), retained, test.topic, len(test.payload), test.retain)
+			}
+		})
+	}
+}
+
+func TestMessageSkipsOtherPackets(t *testing.T) {
+	client, broker := pipe()
+	defer client.c.Close()
+	defer broker.c.Close()
+	go func() {
+		broker.write(0xd0, nil) // ping response
+		body := appendString(nil, "tournament/command")
+		body = append(body, 0, 7) // packet identifier of QoS 1
+		broker.write(typePublish<<4|0x02, append(body, "start"...))
+	}()
+	topic, payload, retained, err := client.message()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topic != "tournament/command" || string(payload) != "start" || retained {
+		t.Errorf("got %q %q retained %v, want the QoS 1 command without its packet identifier", topic, payload, retained)
+	}
+}
+
+func TestSubscribeFraming(t *testing.T) {
+	client, broker := pipe()
+	defer client.c.Close()
+	defer broker.c.Close()
+	go client.subscribe("tournament/command")
+	header, body, err := broker.read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{0, 1}, appendString(nil, "tournament/command")...)
+	want = append(want, 0)
+	if header != typeSubscribe<<4|0x02 || !bytes.Equal(body, want) {
+		t.Errorf("got header %#x body %v, want %#x %v", header, body, typeSubscribe<<4|0x02, want)
+	}
+}
+
+func TestReadRejectsLongLength(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	c := &conn{c: b, r: bufio.NewReader(b)}
+	go a.Write([]byte{typePublish << 4, 0xff, 0xff, 0xff, 0xff, 0x01})
+	if _, _, err := c.read(); err == nil || !strings.Contains(err.Error(), "length") {
+		t.Errorf("got %v, want an invalid length error", err)
+	}
+}
+
+func TestNewBridgeRejectsPasswordWithoutUsername(t *testing.T) {
+	config := DefaultConfig()
+	config.Password = "secret"
+	if _, err := NewBridge(config, control.NewQueue(), engine.State{}); err == nil {
+		t.Error("password without username accepted")
+	}
+	config.Username = "timer"
+	if _, err := NewBridge(config, control.NewQueue(), engine.State{}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"drazil/tournament/engine"
+	"drazil/tournament/link"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// startMaster multicasts every transition and a heartbeat of the timer and
+// answers the time requests of the followers.
+func startMaster() {
+	sender, err := link.NewSender(group, link.NodeName())
+	if err != nil {
+		log.Fatal(err)
+	}
+	addListener(func(e engine.Event) {
+		if err := sender.Send(link.Packet{Event: &e, Snapshot: timer.Snapshot()}); err != nil {
+			log.Printf("link: %v", err)
+		}
+	})
+	go sender.Heartbeat(500*time.Millisecond, timer.Snapshot)
+	if timeServer, err = link.NewTimeServer(syncPort); err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		log.Fatal(timeServer.Run())
+	}()
+}
+
+// startFollower makes the timer follow the master on the group.
+func startFollower() {
+	r, err := link.NewReceiver(group)
+	if err != nil {
+		log.Fatal(err)
+	}
+	receiver = r
+	go func() {
+		if err := r.Run(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	timeClient = link.NewTimeClient(link.NodeName())
+	stopTimeClient = make(chan struct{})
+	go func(c *link.TimeClient, stop <-chan struct{}) {
+		if err := c.Run(r.Master, syncPort, time.Second, stop); err != nil {
+			log.Fatal(err)
+		}
+	}(timeClient, stopTimeClient)
+}
+
+// takeOver turns a backup into the master. The timer already holds the
+// last state of the master, so it continues the running end from there.
+func takeOver() {
+	log.Printf("link: master lost, backup takes over")
+	receiver.Close()
+	receiver = nil
+	close(stopTimeClient)
+	timeClient = nil
+	startMaster()
+}
+
+// follow applies the packets of the master on a slave instance. The time
+// of the snapshot is converted to the local clock, so that the countdown
+// runs from the master's deadline instead of the arrival of the packet.
+// Until the offset is known the arrival time is used. Changes of the state
+// and the signals are taken from the snapshot, so a heartbeat makes up for
+// a lost event packet, and the signals follow the audio offset of this
+// instance; of the event packets only EndFinished is passed on.
+func follow() {
+	for {
+		select {
+		case p := <-receiver.Packets():
+			if offset, _, ok := timeClient.Offset(); ok {
+				p.Snapshot.Taken = p.Snapshot.Taken.Add(-offset)
+			} else {
+				p.Snapshot.Taken = time.Time{}
+			}
+			events := timer.Restore(p.Snapshot)
+			if p.Event != nil && p.Event.Type == engine.EndFinished {
+				events = append(events, *p.Event)
+			}
+			handleEvents(events)
+		default:
+			return
+		}
+	}
+}
+
+// drawLinkStatus shows which node is active on master and backup and a
+// warning when a follower has lost the master.
+func drawLinkStatus(screen *ebiten.Image) {
+	switch {
+	case receiver != nil && receiver.Lost(failover):
+		text.Draw(screen, "LINK LOST", roundFont, 440, 40, colorRed)
+	case backup && receiver != nil:
+		text.Draw(screen, "STANDBY - MASTER ACTIVE", roundFont, 440, 40, colorWhite)
+	case backup:
+		text.Draw(screen, "BACKUP ACTIVE", roundFont, 440, 40, colorYellow)
+	case timeServer != nil:
+		text.Draw(screen, "MASTER ACTIVE", roundFont, 440, 40, colorWhite)
+	}
+	if diagnostics {
+		drawTimeSync(screen)
+	}
+}
+
+// drawTimeSync shows the measured clock offsets: on the master one line
+// per slave, on a slave its own offset to the master.
+func drawTimeSync(screen *ebiten.Image) {
+	lines := []string{"Zeitabgleich"}
+	switch {
+	case timeServer != nil:
+		for _, n := range timeServer.Nodes() {
+			lines = append(lines, fmt.Sprintf("%s %+.1f ms (RTT %.1f ms)", n.Node, milliseconds(n.Offset), milliseconds(n.RTT)))
+		}
+		if len(lines) == 1 {
+			lines = append(lines, "keine Slaves")
+		}
+	case timeClient != nil:
+		if offset, rtt, ok := timeClient.Offset(); ok {
+			lines = append(lines, fmt.Sprintf("Master %s %+.1f ms (RTT %.1f ms)", receiver.Master(), milliseconds(offset), milliseconds(rtt)))
+		} else {
+			lines = append(lines, "Master nicht erreichbar")
+		}
+	default:
+		lines = append(lines, "kein -link")
+	}
+	for i, line := range lines {
+		text.Draw(screen, line, infoFontSmall, 360, 80+i*22, colorYellow)
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
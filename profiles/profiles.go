@@ -0,0 +1,231 @@
+package profiles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"drazil/tournament/engine"
+	"drazil/tournament/input"
+	"drazil/tournament/lights"
+	"drazil/tournament/mqtt"
+	"drazil/tournament/soundbank"
+)
+
+// Rotation modes of a profile. RotationCustom uses the details listed in
+// the profile itself.
+const (
+	RotationSingle = "single"
+	RotationTwo    = "two"
+	RotationThree  = "three"
+	RotationCustom = "custom"
+)
+
+var Rotations = []string{RotationSingle, RotationTwo, RotationThree, RotationCustom}
+
+// Profile is a named set of timing parameters for one tournament format.
+type Profile struct {
+	Name            string          `json:"name"`
+	ActionDuration  int             `json:"actionDuration"`
+	WarnDuration    int             `json:"warnDuration"`
+	PrepareDuration [2]int          `json:"prepareDuration"`
+	Rotation        string          `json:"rotation"`
+	Details         engine.Rotation `json:"details,omitempty"`
+	Schedule        engine.Schedule `json:"schedule,omitempty"`
+}
+
+// File is the content of the configuration file.
+type File struct {
+	Active     string    `json:"active"`
+	Fullscreen bool      `json:"fullscreen"`
+	Volume     int       `json:"volume"`
+	Profiles   []Profile `json:"profiles"`
+	// Volumes scales the volume of single signal roles in percent.
+	Volumes map[string]int `json:"volumes,omitempty"`
+	// AudioOffset is the latency of the sound output in milliseconds.
+	AudioOffset int `json:"audioOffset,omitempty"`
+	// Sounds assigns sounds to the signal roles.
+	Sounds soundbank.Config `json:"sounds"`
+	// Lights are the lamp drivers of this machine.
+	Lights []lights.Config `json:"lights,omitempty"`
+	// Inputs are the command devices of this machine besides the keyboard.
+	Inputs []input.Config `json:"inputs,omitempty"`
+	// MQTT connects the timer to a broker when set.
+	MQTT *mqtt.Config `json:"mqtt,omitempty"`
+}
+
+func Default() *File {
+	return &File{
+		Active:     "WA 70m outdoor 6 arrows/240s",
+		Fullscreen: true,
+		Volume:     100,
+		Profiles: []Profile{
+			{
+				Name: "WA 70m outdoor 6 arrows/240s", ActionDuration: 240, WarnDuration: 30, PrepareDuration: [2]int{10, 20}, Rotation: RotationTwo,
+				Schedule: engine.Schedule{{Name: "Qualification", Distances: []engine.Distance{
+					{Name: "70m", PracticeEnds: 2, Ends: 12},
+				}}},
+			},
+			{
+				Name: "WA 18m indoor 3 arrows/120s", ActionDuration: 120, WarnDuration: 30, PrepareDuration: [2]int{10, 20}, Rotation: RotationTwo,
+				Schedule: engine.Schedule{{Name: "Qualification", Distances: []engine.Distance{
+					{Name: "18m", PracticeEnds: 2, Ends: 10, Break: 900},
+					{Name: "18m", Ends: 10},
+				}}},
+			},
+			{Name: "club training", ActionDuration: 180, WarnDuration: 30, PrepareDuration: [2]int{10, 20}, Rotation: RotationSingle},
+		},
+	}
+}
+
+// Load reads the configuration file at path. A missing file yields the
+// default profiles; settings missing from the file keep their defaults.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default(), nil
+	} else if err != nil {
+		return nil, err
+	}
+	f := Default()
+	f.Profiles = nil
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(f.Profiles) == 0 {
+		return nil, fmt.Errorf("%s: no profiles defined", path)
+	}
+	for _, p := range f.Profiles {
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("%s: profile %q: %w", path, p.Name, err)
+		}
+	}
+	return f, nil
+}
+
+// Save writes the file to a temporary file next to path and renames it into
+// place, so that a crash while writing never leaves a broken file behind.
+func (f *File) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Index returns the position of the named profile or -1.
+func (f *File) Index(name string) int {
+	for i, p := range f.Profiles {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Current returns the active profile, falling back to the first one.
+func (f *File) Current() *Profile {
+	if i := f.Index(f.Active); i >= 0 {
+		return &f.Profiles[i]
+	}
+	return &f.Profiles[0]
+}
+
+// UnmarshalJSON fills the timings missing from a profile with the ones of
+// engine.DefaultConfig.
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	type plain Profile
+	c := engine.DefaultConfig()
+	d := plain{
+		ActionDuration:  c.ActionDuration,
+		WarnDuration:    c.WarnDuration,
+		PrepareDuration: c.PrepareDuration,
+		Rotation:        RotationTwo,
+	}
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	*p = Profile(d)
+	return nil
+}
+
+func (p Profile) validate() error {
+	if p.ActionDuration <= 0 {
+		return errors.New("actionDuration must be positive")
+	}
+	if p.WarnDuration < 0 || p.WarnDuration >= p.ActionDuration {
+		return errors.New("warnDuration must be less than actionDuration")
+	}
+	if p.PrepareDuration[0] < 0 || p.PrepareDuration[1] < 0 {
+		return errors.New("prepareDuration must not be negative")
+	}
+	switch p.Rotation {
+	case "", RotationSingle, RotationTwo, RotationThree:
+	case RotationCustom:
+		if len(p.Details) == 0 {
+			return errors.New("custom rotation without details")
+		}
+		for _, end := range p.Details {
+			if len(end) == 0 {
+				return errors.New("custom rotation with an empty end")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown rotation %q", p.Rotation)
+	}
+	return p.Schedule.Validate()
+}
+
+func (p Profile) Config() engine.Config {
+	return engine.Config{
+		ActionDuration:  p.ActionDuration,
+		WarnDuration:    p.WarnDuration,
+		PrepareDuration: p.PrepareDuration,
+		Rotation:        p.RotationDetails(p.Rotation),
+	}
+}
+
+// RotationDetails resolves a rotation mode to the details it shoots.
+// Unknown modes and a custom mode without details fall back to two
+// details.
+func (p Profile) RotationDetails(mode string) engine.Rotation {
+	switch mode {
+	case RotationSingle:
+		return engine.SingleDetail
+	case RotationThree:
+		return engine.ThreeDetails
+	case RotationCustom:
+		if len(p.Details) > 0 {
+			return p.Details
+		}
+	}
+	return engine.TwoDetails
+}
+
+// SetConfig copies the timing parameters of c into the profile.
+func (p *Profile) SetConfig(c engine.Config) {
+	p.ActionDuration = c.ActionDuration
+	p.WarnDuration = c.WarnDuration
+	p.PrepareDuration = c.PrepareDuration
+}
@@ -0,0 +1,66 @@
+package remote
+
+import (
+	"sync"
+	"time"
+
+	"drazil/tournament/engine"
+)
+
+// Message is pushed to live clients for every engine transition and once
+// per second with Event "tick". Time is the clock of the timer in Unix
+// milliseconds.
+type Message struct {
+	Event  string `json:"event"`
+	Signal string `json:"signal,omitempty"`
+	State  Status `json:"state"`
+	Time   int64  `json:"time"`
+}
+
+func NewMessage(e engine.Event) Message {
+	m := Message{Event: e.Type.String(), State: NewStatus(e.State), Time: now()}
+	if e.Type == engine.SignalTriggered {
+		m.Signal = e.Signal.String()
+	}
+	return m
+}
+
+func now() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// Broadcaster fans messages out to all subscribers. A subscriber that does
+// not keep up loses messages instead of blocking the timer.
+type Broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan Message]struct{}
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subscribers: map[chan Message]struct{}{}}
+}
+
+func (b *Broadcaster) Subscribe() chan Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := make(chan Message, 64)
+	b.subscribers[c] = struct{}{}
+	return c
+}
+
+func (b *Broadcaster) Unsubscribe(c chan Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, c)
+}
+
+func (b *Broadcaster) Publish(m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.subscribers {
+		select {
+		case c <- m:
+		default:
+		}
+	}
+}
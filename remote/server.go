@@ -0,0 +1,156 @@
+package remote
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"drazil/tournament/control"
+	"drazil/tournament/engine"
+	localFonts "drazil/tournament/resources/fonts"
+	localGraphics "drazil/tournament/resources/graphics"
+)
+
+var (
+	//go:embed web/control.html
+	controlPage []byte
+	//go:embed web/display.html
+	displayPage []byte
+)
+
+// Server exposes the timer over HTTP. Commands are passed to the game loop
+// through the queue, so they run the same code as the keyboard.
+type Server struct {
+	queue       *control.Queue
+	timer       *engine.Engine
+	mux         *http.ServeMux
+	broadcaster *Broadcaster
+}
+
+func NewServer(queue *control.Queue, timer *engine.Engine) *Server {
+	s := &Server{queue: queue, timer: timer, mux: http.NewServeMux(), broadcaster: NewBroadcaster()}
+	s.mux.HandleFunc("/", s.handlePage("/", controlPage))
+	s.mux.HandleFunc("/display", s.handlePage("/display", displayPage))
+	s.mux.HandleFunc("/display/digital.ttf", handleResource("font/ttf", localFonts.DigitalFont))
+	s.mux.HandleFunc("/display/red.png", handleResource("image/png", localGraphics.Red2))
+	s.mux.HandleFunc("/display/yellow.png", handleResource("image/png", localGraphics.Yellow2))
+	s.mux.HandleFunc("/display/green.png", handleResource("image/png", localGraphics.Green2))
+	s.mux.HandleFunc("/api/state", s.handleState)
+	s.mux.HandleFunc("/api/events", s.handleEvents)
+	for _, c := range []control.Command{control.Start, control.Cancel, control.Pause, control.Resume, control.Restart} {
+		s.mux.HandleFunc("/api/"+string(c), s.handleCommand(c))
+	}
+	return s
+}
+
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Publish pushes an engine event to all live clients.
+func (s *Server) Publish(e engine.Event) {
+	s.broadcaster.Publish(NewMessage(e))
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	log.Printf("remote control listening on %s", addr)
+	go s.tick()
+	return http.ListenAndServe(addr, s.mux)
+}
+
+func (s *Server) tick() {
+	for range time.Tick(time.Second) {
+		s.broadcaster.Publish(Message{Event: "tick", State: NewStatus(s.timer.State()), Time: now()})
+	}
+}
+
+// handleEvents streams messages as server-sent events, starting with the
+// current state.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	messages := s.broadcaster.Subscribe()
+	defer s.broadcaster.Unsubscribe(messages)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	writeEvent(w, Message{Event: "tick", State: NewStatus(s.timer.State()), Time: now()})
+	flusher.Flush()
+	for {
+		select {
+		case m := <-messages:
+			writeEvent(w, m)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, m Message) {
+	data, _ := json.Marshal(m)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.Event, data)
+}
+
+func (s *Server) handlePage(path string, page []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}
+}
+
+func handleResource(contentType string, data []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Cache-Control", "max-age=86400")
+		w.Write(data)
+	}
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	writeJSON(w, http.StatusOK, NewStatus(s.timer.State()))
+}
+
+func (s *Server) handleCommand(c control.Command) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		if err := s.queue.Send(c); errors.Is(err, control.ErrIgnored) {
+			writeError(w, http.StatusConflict, err)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		log.Printf("remote %s from %s", c, r.RemoteAddr)
+		writeJSON(w, http.StatusOK, NewStatus(s.timer.State()))
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
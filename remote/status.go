@@ -0,0 +1,46 @@
+package remote
+
+import "drazil/tournament/engine"
+
+// Status is the JSON representation of the engine state. Round, Half and
+// End are counted from 1 as on the display.
+type Status struct {
+	Stage    string `json:"stage"`
+	Paused   bool   `json:"paused"`
+	Light    string `json:"light"`
+	Duration int    `json:"duration"`
+	Round    int    `json:"round"`
+	Half     int    `json:"half"`
+	Pair     string `json:"pair"`
+	Session  string `json:"session,omitempty"`
+	Distance string `json:"distance,omitempty"`
+	Practice bool   `json:"practice,omitempty"`
+	End      int    `json:"end,omitempty"`
+	Ends     int    `json:"ends,omitempty"`
+	MakeUp   bool   `json:"makeUp,omitempty"`
+	ShootOff bool   `json:"shootOff,omitempty"`
+	Targets  string `json:"targets,omitempty"`
+}
+
+func NewStatus(s engine.State) Status {
+	status := Status{
+		Stage:    s.Stage.String(),
+		Paused:   s.Paused,
+		Light:    s.Light.String(),
+		Duration: s.Duration,
+		Round:    s.Round + 1,
+		Half:     s.Half + 1,
+		Pair:     s.Pair,
+		Session:  s.Session,
+		Distance: s.Distance,
+		Practice: s.Practice,
+		MakeUp:   s.MakeUp,
+		ShootOff: s.ShootOff,
+		Targets:  s.Targets,
+	}
+	if s.Ends > 0 {
+		status.End = s.End + 1
+		status.Ends = s.Ends
+	}
+	return status
+}
@@ -0,0 +1,48 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// Volume is the master volume in percent and the volume of each signal role
+// in percent of it.
+type Volume struct {
+	Master  int            `json:"master"`
+	Signals map[string]int `json:"signals"`
+}
+
+// Mixer adjusts the volumes of the timer. SetVolume rejects values out of
+// range.
+type Mixer interface {
+	Volume() Volume
+	SetVolume(Volume) error
+}
+
+// SetMixer enables /api/volume: GET returns the volumes, POST changes the
+// master volume and the signals given.
+func (s *Server) SetMixer(m Mixer) {
+	s.mux.HandleFunc("/api/volume", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, m.Volume())
+		case http.MethodPost:
+			v := m.Volume()
+			if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid volume: %w", err))
+				return
+			}
+			if err := m.SetVolume(v); err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			log.Printf("remote volume %d from %s", v.Master, r.RemoteAddr)
+			writeJSON(w, http.StatusOK, m.Volume())
+		default:
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		}
+	})
+}
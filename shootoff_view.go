@@ -0,0 +1,131 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"unicode"
+
+	"drazil/tournament/engine"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// shootOffForm selects the kind of shoot-off before it is started from the
+// tournament view.
+type shootOffForm struct {
+	selected    int
+	team        bool
+	alternating bool
+}
+
+// shootOffDecision asks for the winner once the arrow of a shoot-off on the
+// tournament timer has been shot. With equal scores the arrow closest to the
+// centre decides; the operator enters the target of the winner, which is
+// shown until the next end starts.
+type shootOffDecision struct {
+	open   bool
+	target string
+	winner string
+}
+
+var (
+	shootOff shootOffForm
+	decision shootOffDecision
+)
+
+func (f *shootOffForm) update() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyUp) || inpututil.IsKeyJustPressed(ebiten.KeyDown) {
+		f.selected = 1 - f.selected
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyLeft) || inpututil.IsKeyJustPressed(ebiten.KeyRight) {
+		if f.selected == 0 {
+			f.team = !f.team
+		} else {
+			f.alternating = !f.alternating
+		}
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEnter) {
+		f.start()
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEscape) {
+		view = TournamentView
+	}
+}
+
+// start runs the shoot-off either as a single end on the tournament timer
+// or as an alternating match in the finals view. The schedule position of
+// the tournament is kept in both cases.
+func (f *shootOffForm) start() {
+	config := engine.ShootOffFinals(f.team)
+	if f.alternating {
+		finals.shootOff = engine.NewMatch(config, engine.SystemClock())
+		finals.shootOff.SetSignalOffset(audioOffset)
+		view = FinalsView
+		return
+	}
+	seconds := config.TurnDuration
+	if f.team {
+		seconds = config.Budget
+	}
+	view = TournamentView
+	handleEvents(timer.ShootOff(seconds))
+}
+
+func (f *shootOffForm) draw(screen *ebiten.Image) {
+	kind := "Einzel (40 s)"
+	if f.team {
+		kind = "Team/Mixed (60 s)"
+	}
+	mode := "nein"
+	if f.alternating {
+		mode = "ja"
+	}
+	text.Draw(screen, "Stechen", infoFontLarge, 200, 50, colorWhite)
+	text.Draw(screen, "[PFEILE] Auswahl/Wert  [RETURN] Start  [ESC] Abbrechen", infoFontSmall, 200, 80, colorWhite)
+	for i, line := range []string{"Art: " + kind, "Wechselschiessen: " + mode} {
+		c := colorWhite
+		if i == f.selected {
+			c = colorYellow
+		}
+		text.Draw(screen, line, infoFontLarge, 200, 150+i*45, c)
+	}
+}
+
+func (d *shootOffDecision) start() {
+	*d = shootOffDecision{open: true}
+}
+
+func (d *shootOffDecision) clear() {
+	*d = shootOffDecision{}
+}
+
+func (d *shootOffDecision) shown() bool {
+	return d.open || d.winner != ""
+}
+
+func (d *shootOffDecision) update() {
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && len(d.target) > 0 {
+		d.target = d.target[:len(d.target)-1]
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEnter) && d.target != "" {
+		d.open = false
+		d.winner = d.target
+		log.Printf("shoot-off won by target %s", d.winner)
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyEscape) {
+		d.clear()
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if len(d.target) < 4 && (unicode.IsDigit(r) || unicode.IsLetter(r)) {
+			d.target += strings.ToUpper(string(r))
+		}
+	}
+}
+
+func (d *shootOffDecision) draw(screen *ebiten.Image) {
+	if d.open {
+		text.Draw(screen, "CLOSEST TO CENTRE DECIDES", infoFontLarge, 440, 470, colorYellow)
+		text.Draw(screen, "Scheibe des Siegers  [RETURN] Bestaetigen  [ESC] Schliessen", infoFontSmall, 440, 500, colorWhite)
+		text.Draw(screen, d.target+"_", mediumFont, 400, 700, colorWhite)
+	} else if d.winner != "" {
+		text.Draw(screen, "SHOOT-OFF WINNER", infoFontLarge, 440, 470, colorGreen)
+		text.Draw(screen, d.winner, mediumFont, 400, 700, colorGreen)
+	}
+}
@@ -0,0 +1,301 @@
+package soundbank
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"drazil/tournament/resources/sounds"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+)
+
+// Roles of the sounds. Except for the soundcheck they are the names of
+// the engine signals.
+const (
+	ToLine     = "toLine"
+	Start      = "start"
+	End        = "end"
+	Restart    = "restart"
+	Final      = "final"
+	Emergency  = "emergency"
+	Timeout    = "timeout"
+	Soundcheck = "soundcheck"
+)
+
+var Roles = []string{ToLine, Start, End, Restart, Final, Emergency, Timeout, Soundcheck}
+
+// Embedded are the sounds compiled into the program, by name.
+var Embedded = map[string][]byte{
+	"carHorn":       sounds.CarHorn,
+	"carHornDouble": sounds.CarHornDouble,
+	"carHornTriple": sounds.CarHornTriple,
+	"horn":          sounds.Horn,
+	"buzzer":        sounds.Buzzer,
+	"buzzer2":       sounds.Buzzer2,
+}
+
+var defaults = map[string]Sequence{
+	ToLine:     {"carHornDouble"},
+	Start:      {"carHorn"},
+	End:        {"carHornTriple"},
+	Restart:    {"buzzer2"},
+	Final:      {"buzzer2"},
+	Emergency:  {"carHornTriple", "carHornDouble"},
+	Timeout:    {"buzzer2"},
+	Soundcheck: {"horn"},
+}
+
+// decoders decode sound files by extension.
+var decoders = map[string]func(*audio.Context, io.Reader) (io.ReadSeeker, error){
+	".wav": func(c *audio.Context, r io.Reader) (io.ReadSeeker, error) {
+		return wav.Decode(c, r)
+	},
+	".ogg": func(c *audio.Context, r io.Reader) (io.ReadSeeker, error) {
+		return vorbis.Decode(c, r)
+	},
+	".mp3": func(c *audio.Context, r io.Reader) (io.ReadSeeker, error) {
+		return mp3.Decode(c, r)
+	},
+}
+
+// Sequence is a list of sounds played one after another. Each is the name
+// of an embedded sound or a file. In the configuration a single sound may
+// be given as a plain string.
+type Sequence []string
+
+func (s *Sequence) UnmarshalJSON(data []byte) error {
+	var name string
+	if json.Unmarshal(data, &name) == nil {
+		*s = Sequence{name}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(s))
+}
+
+// Pattern composes a signal of Blasts blasts of the blast sample, each
+// Length milliseconds long and followed by a pause of Gap milliseconds.
+type Pattern struct {
+	Blasts int `json:"blasts"`
+	Length int `json:"length"`
+	Gap    int `json:"gap"`
+}
+
+// WorldArchery are the signals of the WA rules: two blasts to the line,
+// one to start, three to end and five or more to stop shooting.
+var WorldArchery = map[string]Pattern{
+	ToLine:    {Blasts: 2, Length: 1000, Gap: 500},
+	Start:     {Blasts: 1, Length: 1000, Gap: 500},
+	End:       {Blasts: 3, Length: 1000, Gap: 500},
+	Emergency: {Blasts: 5, Length: 1000, Gap: 500},
+}
+
+// Config assigns sounds to roles. When Blast is set, the roles with a
+// pattern are composed from that single sample; Patterns overrides the WA
+// patterns per role. Roles listed in Roles use their sounds instead, all
+// others keep their embedded sound. Files are looked up in Directory.
+// Tones adds synthesised sounds, which are used by name like the embedded
+// ones.
+type Config struct {
+	Directory string              `json:"directory,omitempty"`
+	Roles     map[string]Sequence `json:"roles,omitempty"`
+	Blast     string              `json:"blast,omitempty"`
+	Patterns  map[string]Pattern  `json:"patterns,omitempty"`
+	Tones     map[string]Tone     `json:"tones,omitempty"`
+}
+
+// step plays a sound for length, or to its end if length is 0, and then
+// waits for gap.
+type step struct {
+	player *audio.Player
+	length time.Duration
+	gap    time.Duration
+}
+
+// pause separates signals that follow each other in the queue.
+const pause = 500 * time.Millisecond
+
+// Bank holds the sounds of every role. Signals are played one after
+// another from a queue, so a signal triggered while another one is still
+// sounding is delayed rather than lost.
+type Bank struct {
+	context   *audio.Context
+	directory string
+	tones     map[string]Tone
+	mu        sync.Mutex
+	roles     map[string][]step
+	queue     chan []step
+}
+
+// New loads the sounds of all roles. A sound that cannot be loaded is
+// logged and the role falls back to its embedded sound.
+func New(context *audio.Context, config Config) *Bank {
+	b := &Bank{
+		context:   context,
+		directory: config.Directory,
+		tones:     map[string]Tone{"synthHorn": Horn, "synthBeep": Beep},
+		roles:     map[string][]step{},
+		queue:     make(chan []step, 16),
+	}
+	for name, t := range config.Tones {
+		b.tones[name] = t
+	}
+	patterns := map[string]Pattern{}
+	if config.Blast != "" {
+		for role, p := range WorldArchery {
+			patterns[role] = p
+		}
+		for role, p := range config.Patterns {
+			patterns[role] = p
+		}
+	}
+	for _, role := range Roles {
+		var steps []step
+		var err error
+		if sequence, ok := config.Roles[role]; ok {
+			steps, err = b.load(sequence)
+		} else if p, ok := patterns[role]; ok && p.Blasts > 0 {
+			steps, err = b.compose(config.Blast, p)
+		}
+		if err != nil {
+			log.Printf("sound %s: %v, using embedded sound", role, err)
+			steps = nil
+		}
+		if len(steps) == 0 {
+			if steps, err = b.load(defaults[role]); err != nil {
+				log.Printf("sound %s: %v", role, err)
+			}
+		}
+		b.roles[role] = steps
+	}
+	go b.run()
+	return b
+}
+
+func (b *Bank) load(sequence Sequence) ([]step, error) {
+	var steps []step
+	for _, name := range sequence {
+		stream, err := b.decode(name)
+		if err != nil {
+			return nil, err
+		}
+		player, err := b.context.NewPlayer(stream)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step{player: player})
+	}
+	return steps, nil
+}
+
+// compose builds a pattern from a blast sample. The sample is looped, so
+// that blasts may be longer than the recording.
+func (b *Bank) compose(blast string, p Pattern) ([]step, error) {
+	stream, err := b.decode(blast)
+	if err != nil {
+		return nil, err
+	}
+	var src io.Reader = stream
+	if s, ok := stream.(interface{ Length() int64 }); ok && p.Length > 0 {
+		src = audio.NewInfiniteLoop(stream, s.Length())
+	}
+	player, err := b.context.NewPlayer(src)
+	if err != nil {
+		return nil, err
+	}
+	steps := make([]step, p.Blasts)
+	for i := range steps {
+		steps[i] = step{
+			player: player,
+			length: time.Duration(p.Length) * time.Millisecond,
+			gap:    time.Duration(p.Gap) * time.Millisecond,
+		}
+	}
+	return steps, nil
+}
+
+// decode returns the stream of a tone, an embedded sound or a file.
+func (b *Bank) decode(name string) (io.ReadSeeker, error) {
+	if t, ok := b.tones[name]; ok {
+		return t.Synthesize(b.context.SampleRate())
+	}
+	if data, ok := Embedded[name]; ok {
+		return wav.Decode(b.context, bytes.NewReader(data))
+	}
+	decoder, ok := decoders[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported sound format", name)
+	}
+	data, err := os.ReadFile(filepath.Join(b.directory, name))
+	if err != nil {
+		return nil, err
+	}
+	stream, err := decoder(b.context, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return stream, nil
+}
+
+// Play queues the sounds of a role.
+func (b *Bank) Play(role string) {
+	b.mu.Lock()
+	steps := b.roles[role]
+	b.mu.Unlock()
+	if len(steps) == 0 {
+		return
+	}
+	select {
+	case b.queue <- steps:
+	default:
+		log.Printf("sound %s: queue full, dropped", role)
+	}
+}
+
+func (b *Bank) run() {
+	for steps := range b.queue {
+		for _, s := range steps {
+			s.player.Rewind()
+			s.player.Play()
+			if s.length > 0 {
+				time.Sleep(s.length)
+				s.player.Pause()
+			} else {
+				for s.player.IsPlaying() {
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+			time.Sleep(s.gap)
+		}
+		time.Sleep(pause)
+	}
+}
+
+// SetVolume sets the volume of all roles. master is the gain between 0 and
+// 1, levels scales single roles in percent; roles not listed play at the
+// master volume. The result is limited to 1, the unchanged sound.
+func (b *Bank) SetVolume(master float64, levels map[string]int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for role, steps := range b.roles {
+		volume := master
+		if level, ok := levels[role]; ok {
+			volume = master * float64(level) / 100
+		}
+		volume = math.Max(0, math.Min(volume, 1))
+		for _, s := range steps {
+			s.player.SetVolume(volume)
+		}
+	}
+}
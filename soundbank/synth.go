@@ -0,0 +1,68 @@
+package soundbank
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+)
+
+// Tone is a synthesised sound: a sine at Frequency Hz with the overtones
+// given by Harmonics, the amplitudes of the 2nd, 3rd, ... partial relative
+// to the fundamental. It rises in Attack and fades in Release
+// milliseconds of its Duration.
+type Tone struct {
+	Frequency float64   `json:"frequency"`
+	Harmonics []float64 `json:"harmonics,omitempty"`
+	Attack    int       `json:"attack"`
+	Release   int       `json:"release"`
+	Duration  int       `json:"duration"`
+}
+
+// Horn is a low, brassy tone that carries well over a range; Beep a short
+// pure tone.
+var (
+	Horn = Tone{Frequency: 415, Harmonics: []float64{0.7, 0.5, 0.35, 0.2, 0.1}, Attack: 40, Release: 120, Duration: 1000}
+	Beep = Tone{Frequency: 1000, Attack: 5, Release: 10, Duration: 300}
+)
+
+// stream is synthesised audio in memory.
+type stream struct {
+	*bytes.Reader
+}
+
+func (s stream) Length() int64 {
+	return s.Size()
+}
+
+// Synthesize renders the tone as 16 bit stereo PCM, the format of the
+// audio context.
+func (t Tone) Synthesize(sampleRate int) (io.ReadSeeker, error) {
+	if t.Frequency <= 0 || t.Duration <= 0 {
+		return nil, errors.New("tone needs a frequency and a duration")
+	}
+	n := sampleRate * t.Duration / 1000
+	attack := sampleRate * t.Attack / 1000
+	release := sampleRate * t.Release / 1000
+	total := 1.0
+	for _, h := range t.Harmonics {
+		total += math.Abs(h)
+	}
+	data := make([]byte, 0, n*4)
+	for i := 0; i < n; i++ {
+		x := 2 * math.Pi * t.Frequency * float64(i) / float64(sampleRate)
+		v := math.Sin(x)
+		for k, h := range t.Harmonics {
+			v += h * math.Sin(float64(k+2)*x)
+		}
+		gain := 1.0
+		if i < attack {
+			gain = float64(i) / float64(attack)
+		} else if i >= n-release {
+			gain = float64(n-i) / float64(release)
+		}
+		sample := int16(v / total * gain * 0.8 * math.MaxInt16)
+		data = append(data, byte(sample), byte(sample>>8), byte(sample), byte(sample>>8))
+	}
+	return stream{bytes.NewReader(data)}, nil
+}
@@ -11,13 +11,20 @@ import (
 	"os"
 	"time"
 
+	"drazil/tournament/control"
+	"drazil/tournament/engine"
+	"drazil/tournament/input"
+	"drazil/tournament/lights"
+	"drazil/tournament/link"
+	"drazil/tournament/mqtt"
+	"drazil/tournament/profiles"
+	"drazil/tournament/remote"
 	localFonts "drazil/tournament/resources/fonts"
 	localGraphics "drazil/tournament/resources/graphics"
-	localSounds "drazil/tournament/resources/sounds"
+	"drazil/tournament/soundbank"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/audio/wav"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	"golang.org/x/image/font"
@@ -30,64 +37,60 @@ const (
 	zero         = "000"
 )
 
-type Stage int
 type View int
 
-const (
-	Halt         Stage = 0
-	InitPrepare        = 1
-	StartPrepare       = 2
-	InitAction         = 3
-	StartAction        = 4
-)
-
 const (
 	MainView          View = 0
 	HelpView               = 1
 	ConfigurationView      = 2
 	TournamentView         = 3
+	MakeUpView             = 4
+	FinalsView             = 5
+	ShootOffView           = 6
+	CalibrationView        = 7
 )
 
 var (
-	actionDuration int = 120
-	warnDuration   int = 30
 	//showTournamentView     = false
-	cancel          = false
-	fullscreen      = true
-	tournamentFont  font.Face
-	infoFontLarge   font.Face
-	infoFontSmall   font.Face
-	roundFont       font.Face
-	displayText     = ""
-	colorWhite      = color.RGBA{255, 255, 255, 255}
-	colorDarkGray   = color.RGBA{50, 50, 50, 255}
-	colorBlack      = color.RGBA{0, 0, 0, 255}
-	colorYellow     = color.RGBA{255, 255, 0, 255}
-	colorRed        = color.RGBA{255, 0, 0, 255}
-	colorGreen      = color.RGBA{0, 255, 0, 255}
-	countDownColor  = colorYellow
-	pairColor       = colorWhite
-	startTime       time.Time
-	endTime         time.Time
-	pair            = [...]string{"A-B", "C-D", "C-D", "A-B"}
-	prepareDuration = [...]int{10, 20}
-	duration        int
-	half            int = 0
-	round           int = 0
-	stage           Stage
-	view            View
-	displayFormat   string
-	audioContext    *audio.Context
-	testPlayer      *audio.Player
-	signalPlayer1   *audio.Player
-	signalPlayer2   *audio.Player
-	signalPlayer3   *audio.Player
-	buzzerPlayer    *audio.Player
-	logo            *ebiten.Image
-	red             *ebiten.Image
-	green           *ebiten.Image
-	yellow          *ebiten.Image
-	signalLight     *ebiten.Image
+	fullscreen     = true
+	tournamentFont font.Face
+	infoFontLarge  font.Face
+	infoFontSmall  font.Face
+	roundFont      font.Face
+	mediumFont     font.Face
+	displayText    = ""
+	colorWhite     = color.RGBA{255, 255, 255, 255}
+	colorDarkGray  = color.RGBA{50, 50, 50, 255}
+	colorBlack     = color.RGBA{0, 0, 0, 255}
+	colorYellow    = color.RGBA{255, 255, 0, 255}
+	colorRed       = color.RGBA{255, 0, 0, 255}
+	colorGreen     = color.RGBA{0, 255, 0, 255}
+	pairColor      = colorWhite
+	timer          *engine.Engine
+	match          *engine.Match
+	commands       = control.NewQueue()
+	listeners      []func(engine.Event)
+	inputs         []input.Source
+	receiver       *link.Receiver
+	timeServer     *link.TimeServer
+	timeClient     *link.TimeClient
+	stopTimeClient chan struct{}
+	diagnostics    bool
+	group          string
+	syncPort       int
+	backup         bool
+	failover       time.Duration
+	config         = engine.DefaultConfig()
+	configFile     string
+	settingsFile   *profiles.File
+	view           View
+	displayFormat  string
+	audioContext   *audio.Context
+	soundBank      *soundbank.Bank
+	logo           *ebiten.Image
+	red            *ebiten.Image
+	green          *ebiten.Image
+	yellow         *ebiten.Image
 )
 
 func init() {
@@ -103,28 +106,7 @@ func init() {
 	img, _, err = image.Decode(bytes.NewReader(localGraphics.Yellow2))
 	yellow = ebiten.NewImageFromImage(img)
 
-	signalLight = red
-
 	audioContext = audio.NewContext(48000)
-	signalSound1, err := wav.Decode(audioContext, bytes.NewReader(localSounds.CarHorn))
-	signalPlayer1, err = audioContext.NewPlayer(signalSound1)
-	signalPlayer1.SetVolume(20)
-
-	signalSound2, err := wav.Decode(audioContext, bytes.NewReader(localSounds.CarHornDouble))
-	signalPlayer2, err = audioContext.NewPlayer(signalSound2)
-	signalPlayer2.SetVolume(20)
-
-	signalSound3, err := wav.Decode(audioContext, bytes.NewReader(localSounds.CarHornTriple))
-	signalPlayer3, err = audioContext.NewPlayer(signalSound3)
-	signalPlayer3.SetVolume(20)
-
-	testSound, err := wav.Decode(audioContext, bytes.NewReader(localSounds.Horn))
-	testPlayer, err = audioContext.NewPlayer(testSound)
-	testPlayer.SetVolume(20)
-
-	buzzerSound, err := wav.Decode(audioContext, bytes.NewReader(localSounds.Buzzer2))
-	buzzerPlayer, err = audioContext.NewPlayer(buzzerSound)
-	buzzerPlayer.SetVolume(20)
 
 	digitalFont, err := opentype.Parse(localFonts.DigitalFont)
 	tournamentFont, err = opentype.NewFace(digitalFont, &opentype.FaceOptions{
@@ -138,6 +120,11 @@ func init() {
 		DPI:     72,
 		Hinting: font.HintingFull,
 	})
+	mediumFont, err = opentype.NewFace(digitalFont, &opentype.FaceOptions{
+		Size:    260,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
 	textFont, err := opentype.Parse(localFonts.OspDin)
 	infoFontLarge, err = opentype.NewFace(textFont, &opentype.FaceOptions{
 		Size:    32,
@@ -160,28 +147,53 @@ type Tournament struct {
 
 func (t *Tournament) Update() error {
 
-	if ebiten.IsKeyPressed(ebiten.KeyH) {
+	if view == MakeUpView {
+		makeUp.update()
+	} else if view == FinalsView {
+		finals.update()
+	} else if view == ShootOffView {
+		shootOff.update()
+	} else if view == CalibrationView {
+		calibration.update()
+	} else if view == TournamentView && decision.open {
+		decision.update()
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyL) && view != ConfigurationView && idle() {
+		calibration.open()
+	} else if ebiten.IsKeyPressed(ebiten.KeyH) {
 		view = HelpView
 	} else if ebiten.IsKeyPressed(ebiten.KeyK) {
+		if view != ConfigurationView {
+			configuration.open()
+		}
 		view = ConfigurationView
+	} else if view == ConfigurationView && configuration.update() {
+		// key consumed by the settings editor
 	} else if inpututil.IsKeyJustReleased(ebiten.KeyEnter) && view == TournamentView {
-		countDownColor = colorYellow
-		stage = Stage(InitPrepare)
+		execute(control.Start)
 	} else if inpututil.IsKeyJustReleased(ebiten.KeyT) {
 		view = TournamentView
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyF) {
+		view = FinalsView
 	} else if inpututil.IsKeyJustReleased(ebiten.KeyEscape) && view == TournamentView {
-		cancel = true
+		execute(control.Cancel)
 	} else if inpututil.IsKeyJustReleased(ebiten.KeyEscape) && view == HelpView {
 		view = MainView
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyP) && view == TournamentView {
+		if timer.State().Paused {
+			execute(control.Resume)
+		} else {
+			execute(control.Pause)
+		}
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyM) && view == TournamentView && !timer.State().Stage.Running() {
+		makeUp.open()
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyO) && view == TournamentView && !timer.State().Stage.Running() {
+		view = ShootOffView
 	} else if inpututil.IsKeyJustReleased(ebiten.KeyN) && view == TournamentView {
-		round = 0
-		half = 0
-		stage = Stage(Halt)
-		PlaySound(10)
-		signalLight = red
-		duration = 0
+		execute(control.Restart)
 	} else if inpututil.IsKeyJustReleased(ebiten.KeyS) {
-		PlaySound(0)
+		volumes.soundcheck()
+	} else if inpututil.IsKeyJustReleased(ebiten.KeyD) {
+		diagnostics = !diagnostics
 	} else if inpututil.IsKeyJustReleased(ebiten.KeyF11) {
 		fullscreen = !fullscreen
 		ebiten.SetFullscreen(fullscreen)
@@ -192,119 +204,208 @@ func (t *Tournament) Update() error {
 		os.Exit(0)
 	}
 
+	if view != ConfigurationView && view != MakeUpView && !(view == TournamentView && decision.open) {
+		volumes.update()
+	}
+	volumes.save()
+	pollInputs()
+	commands.Drain(execute)
+	if receiver != nil {
+		follow()
+		handleEvents(timer.Countdown())
+		if backup && receiver.Master() != nil && receiver.Lost(failover) {
+			takeOver()
+		}
+	} else {
+		handleEvents(timer.Tick())
+	}
 	return nil
 }
-func PlaySound(count int) {
-	if count == 0 {
-		if !testPlayer.IsPlaying() {
-			testPlayer.Rewind()
-			testPlayer.Play()
+
+// execute runs an operator command, whether it comes from the keyboard or
+// from a remote source.
+func execute(c control.Command) error {
+	if receiver != nil {
+		return control.ErrIgnored
+	}
+	var events []engine.Event
+	switch c {
+	case control.Start:
+		events = timer.Start()
+	case control.Cancel:
+		events = timer.Cancel()
+	case control.Pause, control.Emergency:
+		events = timer.Pause()
+	case control.Resume:
+		events = timer.Resume()
+	case control.Restart:
+		events = timer.Reset()
+	default:
+		return control.ErrUnknown
+	}
+	if events == nil {
+		return control.ErrIgnored
+	}
+	handleEvents(events)
+	return nil
+}
+
+// pollInputs executes the commands of the input devices besides the
+// keyboard.
+func pollInputs() {
+	for _, source := range inputs {
+		for _, c := range source.Poll() {
+			if err := execute(c); err != nil {
+				log.Printf("input: %s: %s: %v", source.Name(), c, err)
+			} else {
+				log.Printf("input: %s: %s", source.Name(), c)
+			}
 		}
-	} else if count == 1 {
-		if !signalPlayer1.IsPlaying() {
-			signalPlayer1.Rewind()
-			signalPlayer1.Play()
+	}
+}
+
+// addListener registers a function that is called for every engine event.
+func addListener(l func(engine.Event)) {
+	listeners = append(listeners, l)
+}
+
+// addLightDriver feeds every change of the signal light and stage to a
+// lamp driver.
+func addLightDriver(driver lights.Driver) {
+	update := func(state engine.State) {
+		if err := driver.Update(state); err != nil {
+			log.Printf("lights: %v", err)
 		}
-	} else if count == 2 {
-		if !signalPlayer2.IsPlaying() {
-			signalPlayer2.Rewind()
-			signalPlayer2.Play()
+	}
+	update(timer.State())
+	addListener(func(e engine.Event) {
+		if e.Type == engine.LightChanged || e.Type == engine.StageChanged {
+			update(e.State)
 		}
-	} else if count == 3 {
-		if !signalPlayer3.IsPlaying() {
-			signalPlayer3.Rewind()
-			signalPlayer3.Play()
+	})
+}
+
+func handleEvents(events []engine.Event) {
+	for _, e := range events {
+		for _, l := range listeners {
+			l(e)
 		}
-	} else if count == 10 {
-		if !buzzerPlayer.IsPlaying() {
-			buzzerPlayer.Rewind()
-			buzzerPlayer.Play()
+		if e.Type == engine.EndFinished {
+			logEnd(e.State)
+			if e.State.ShootOff {
+				decision.start()
+			}
+		} else if e.Type == engine.StageChanged && e.State.Stage == engine.Prepare {
+			decision.clear()
+		} else if e.Type == engine.SignalTriggered {
+			playSignal(e.Signal)
 		}
-	} else {
 	}
+}
+
+// playSignal plays the sound of a signal; the roles of the sound bank are
+// named after the signals.
+func playSignal(signal engine.Signal) {
+	soundBank.Play(signal.String())
+}
 
+func logEnd(s engine.State) {
+	if s.MakeUp {
+		log.Printf("make-up end finished: targets %s", s.Targets)
+	} else if s.ShootOff {
+		log.Printf("shoot-off finished")
+	} else if s.Ends > 0 {
+		log.Printf("end %d/%d %s finished: round %d, half %d, %s", s.End+1, s.Ends, s.Distance, s.Round+1, s.Half+1, s.Pair)
+	} else {
+		log.Printf("end finished: round %d, half %d, %s", s.Round+1, s.Half+1, s.Pair)
+	}
 }
 
 func (t *Tournament) Draw(screen *ebiten.Image) {
 	screen.Fill(colorBlack)
 
 	if view == TournamentView {
-		if stage == InitPrepare {
-			stage = Stage(StartPrepare)
-			signalLight = red
-			duration = prepareDuration[half]
-			PlaySound(2)
-			startTime = time.Now()
-			endTime = startTime
-			endTime.Add(time.Second * time.Duration(duration))
-		} else if stage == StartPrepare {
-			duration = prepareDuration[half] - int(time.Now().Sub(endTime).Seconds())
-			if duration == 0 {
-				PlaySound(1)
-				stage = Stage(InitAction)
-			}
-		} else if stage == InitAction {
-			stage = Stage(StartAction)
-			signalLight = green
-			duration = actionDuration
-			startTime = time.Now()
-			endTime = startTime
-			endTime.Add(time.Second * time.Duration(actionDuration))
-		} else if stage == StartAction {
-			duration = actionDuration - int(time.Now().Sub(endTime).Seconds())
-			if duration <= warnDuration {
-				countDownColor = colorRed
-				signalLight = yellow
-			}
-			if duration == 0 || cancel {
-				cancel = false
-				countDownColor = colorYellow
-				signalLight = red
-
-				if round == 0 || round == 2 {
-					half = 1
-					PlaySound(2)
-					stage = Stage(InitPrepare)
-				} else {
-					half = 0
-					PlaySound(3)
-					stage = Stage(Halt)
-				}
-				round++
-				if round > 3 {
-					round = 0
-					half = 0
-				}
-			}
-		}
-		timeLeft := fmt.Sprintf("%3d", duration)
-		roundText := fmt.Sprintf("ROUND:%1d", round+1)
-		halfText := fmt.Sprintf("HALF :%1d", half+1)
-		clockText := fmt.Sprintf("%02d:%02d:%02d", time.Now().Hour(), time.Now().Minute(), time.Now().Second())
-		text.Draw(screen, zero, tournamentFont, 400, 350, colorDarkGray)
-		text.Draw(screen, timeLeft, tournamentFont, 400, 350, countDownColor)
-		text.Draw(screen, pair[round], tournamentFont, 400, 700, colorWhite)
-		text.Draw(screen, roundText, roundFont, 440, 395, colorWhite)
-		text.Draw(screen, halfText, roundFont, 640, 395, colorWhite)
-		text.Draw(screen, clockText, roundFont, 840, 395, colorWhite)
-
-		var op = &ebiten.DrawImageOptions{}
-		op.GeoM.Scale(float64(7), float64(13))
-		op.GeoM.Translate(float64(0), float64(50))
-		screen.DrawImage(signalLight, op)
-
+		drawTournament(screen, timer.State())
 	} else if view == MainView {
 		text.Draw(screen, "Turnier Timer", infoFontLarge, 200, 50, colorWhite)
 		text.Draw(screen, "BSV Eppinghoven 1743 e.V.", infoFontSmall, 200, 80, colorWhite)
-		text.Draw(screen, "[T]urnier\n[H]ilfe\n[K]onfiguration\nE[x]it", infoFontLarge, 200, 150, colorWhite)
+		text.Draw(screen, "[T]urnier\n[F]inale\n[H]ilfe\n[K]onfiguration\n[L]atenz\nE[x]it", infoFontLarge, 200, 150, colorWhite)
 		op := &ebiten.DrawImageOptions{}
 		op.GeoM.Translate(float64(0), float64(30))
 		screen.DrawImage(logo, op)
 	} else if view == ConfigurationView {
+		configuration.draw(screen)
+	} else if view == MakeUpView {
+		makeUp.draw(screen)
+	} else if view == FinalsView {
+		finals.draw(screen)
+	} else if view == ShootOffView {
+		shootOff.draw(screen)
+	} else if view == CalibrationView {
+		calibration.draw(screen)
 	} else if view == HelpView {
 		text.Draw(screen, "Turnier Timer Hilfe", infoFontLarge, 200, 50, colorWhite)
-		text.Draw(screen, "[T]urnier Ansicht\n  - [RETURN] Start\n  - [ESC] Passe vorzeitig beenden\n  - [N]eustart\n[S]oundcheck\n[F11] Vollbild\n[H]ilfe anzeigen\n[K]onfiguration\nE[x]it", infoFontLarge, 200, 150, colorWhite)
+		text.Draw(screen, "[T]urnier Ansicht\n  - [RETURN] Start\n  - [ESC] Passe vorzeitig beenden\n  - [P]ause/Fortsetzen\n  - [M] Nachschiessen\n  - [O] Stechen\n  - [N]eustart\n[F]inale Ansicht\n[S]oundcheck, [+]/[-]/[V] Lautstaerke\n[D] Zeitabgleich anzeigen\n[F11] Vollbild, [L]atenz kalibrieren\n[H]ilfe anzeigen\n[K]onfiguration\n  - [PFEILE] Auswahl/Wert\n  - [RETURN] Uebernehmen\nE[x]it", infoFontLarge, 200, 150, colorWhite)
+	}
+	volumes.draw(screen)
+}
+
+func lightImage(light engine.Light) *ebiten.Image {
+	switch light {
+	case engine.Green:
+		return green
+	case engine.Yellow:
+		return yellow
 	}
+	return red
+}
+
+func drawTournament(screen *ebiten.Image, state engine.State) {
+	countDownColor := colorYellow
+	if state.Light == engine.Yellow {
+		countDownColor = colorRed
+	}
+	signalLight := lightImage(state.Light)
+	timeLeft := fmt.Sprintf("%3d", state.Duration)
+	roundText := fmt.Sprintf("ROUND:%1d", state.Round+1)
+	halfText := fmt.Sprintf("HALF :%1d", state.Half+1)
+	clockText := fmt.Sprintf("%02d:%02d:%02d", time.Now().Hour(), time.Now().Minute(), time.Now().Second())
+	text.Draw(screen, zero, tournamentFont, 400, 350, colorDarkGray)
+	text.Draw(screen, timeLeft, tournamentFont, 400, 350, countDownColor)
+	if decision.shown() && !state.Stage.Running() {
+		decision.draw(screen)
+	} else if !state.Paused && !state.MakeUp && !state.ShootOff {
+		text.Draw(screen, state.Pair, tournamentFont, 400, 700, colorWhite)
+	} else if state.Paused && time.Now().UnixNano()/int64(500*time.Millisecond)%2 == 0 {
+		text.Draw(screen, "STOP", mediumFont, 400, 650, colorRed)
+	}
+	text.Draw(screen, roundText, roundFont, 440, 395, colorWhite)
+	text.Draw(screen, halfText, roundFont, 640, 395, colorWhite)
+	text.Draw(screen, clockText, roundFont, 840, 395, colorWhite)
+	if state.MakeUp {
+		text.Draw(screen, "MAKE-UP TARGETS "+state.Targets, roundFont, 440, 750, colorWhite)
+	} else if state.ShootOff {
+		text.Draw(screen, "SHOOT-OFF", roundFont, 440, 750, colorWhite)
+	} else if state.Stage == engine.Finished {
+		text.Draw(screen, "FINISHED", roundFont, 440, 750, colorWhite)
+	} else if state.Ends > 0 {
+		endText := "END"
+		if state.Practice {
+			endText = "PRACTICE"
+		}
+		endText = fmt.Sprintf("%s %d/%d - %s", endText, state.End+1, state.Ends, state.Distance)
+		if state.Stage == engine.Break {
+			endText = "BREAK - " + endText
+		}
+		text.Draw(screen, endText, roundFont, 440, 750, colorWhite)
+	}
+
+	drawLinkStatus(screen)
+
+	var op = &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(7), float64(13))
+	op.GeoM.Translate(float64(0), float64(50))
+	screen.DrawImage(signalLight, op)
 }
 
 func getCenteredX(content string, screen ebiten.Image, df font.Face) int {
@@ -320,15 +421,112 @@ func (t *Tournament) Layout(outsideWidth, outsideHeight int) (int, int) {
 
 func main() {
 
+	var profile, httpAddr, linkMode, broker string
+	var actionDuration, warnDuration int
 	flag.BoolVar(&fullscreen, "f", true, "Fullscreen Mode")
 	flag.IntVar(&actionDuration, "d", 120, "Action time (seconds)")
 	flag.IntVar(&warnDuration, "w", 30, "Warn time (seconds)")
+	flag.StringVar(&configFile, "c", "tournament.json", "Configuration file")
+	flag.StringVar(&profile, "p", "", "Profile name")
+	flag.StringVar(&httpAddr, "http", "", "Remote control address, e.g. :8080")
+	flag.StringVar(&broker, "mqtt", "", "MQTT broker address, e.g. localhost:1883")
+	flag.StringVar(&linkMode, "link", "", "Synchronisation with other instances: master, slave or backup")
+	flag.StringVar(&group, "group", link.DefaultGroup, "Multicast group for -link")
+	flag.IntVar(&syncPort, "syncport", link.DefaultSyncPort, "UDP port of the master for clock synchronisation")
+	flag.DurationVar(&failover, "failover", 3*time.Second, "Time without master after which a backup takes over")
 	flag.Parse()
 
+	var err error
+	if settingsFile, err = profiles.Load(configFile); err != nil {
+		log.Fatal(err)
+	}
+	if profile != "" {
+		if settingsFile.Index(profile) < 0 {
+			log.Fatalf("unknown profile %q", profile)
+		}
+		settingsFile.Active = profile
+	}
+	config = settingsFile.Current().Config()
+	soundBank = soundbank.New(audioContext, settingsFile.Sounds)
+	volumes.init(settingsFile.Volume, settingsFile.Volumes)
+	explicit := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if !explicit["f"] {
+		fullscreen = settingsFile.Fullscreen
+	}
+	if explicit["d"] {
+		config.ActionDuration = actionDuration
+	}
+	if explicit["w"] {
+		config.WarnDuration = warnDuration
+	}
+
+	timer = engine.New(config, engine.SystemClock())
+	if err := timer.SetSchedule(settingsFile.Current().Schedule); err != nil {
+		log.Fatal(err)
+	}
+	match = engine.NewMatch(engine.IndividualFinals(), engine.SystemClock())
+	setAudioOffset(time.Duration(clamp(settingsFile.AudioOffset, -500, 500)) * time.Millisecond)
+
+	for _, c := range settingsFile.Lights {
+		driver, err := lights.Open(c)
+		if err != nil {
+			log.Fatal(err)
+		}
+		addLightDriver(driver)
+	}
+
+	for _, c := range settingsFile.Inputs {
+		source, err := input.Open(c)
+		if err != nil {
+			log.Fatal(err)
+		}
+		inputs = append(inputs, source)
+	}
+
+	switch linkMode {
+	case "master":
+		startMaster()
+	case "slave":
+		startFollower()
+	case "backup":
+		backup = true
+		startFollower()
+	case "":
+	default:
+		log.Fatalf("unknown link mode %q", linkMode)
+	}
+
+	if broker != "" || settingsFile.MQTT != nil {
+		c := mqtt.DefaultConfig()
+		if settingsFile.MQTT != nil {
+			c = *settingsFile.MQTT
+		}
+		if broker != "" {
+			c.Broker = broker
+		}
+		bridge, err := mqtt.NewBridge(c, commands, timer.State())
+		if err != nil {
+			log.Fatal(err)
+		}
+		addListener(bridge.Publish)
+		go bridge.Run()
+	}
+
+	if httpAddr != "" {
+		server := remote.NewServer(commands, timer)
+		server.SetMixer(&volumes)
+		addListener(server.Publish)
+		go func() {
+			log.Fatal(server.ListenAndServe(httpAddr))
+		}()
+	}
+
 	ebiten.SetWindowSize(screenWidth, screenHeight)
 	ebiten.SetWindowTitle("Archery Tournament Timer")
 	ebiten.SetFullscreen(fullscreen)
 	ebiten.SetCursorMode(ebiten.CursorModeHidden)
+	ebiten.SetRunnableOnUnfocused(true)
 
 	if err := ebiten.RunGame(&Tournament{}); err != nil {
 		log.Fatal(err)
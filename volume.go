@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"sync"
+	"time"
+
+	"drazil/tournament/remote"
+	"drazil/tournament/soundbank"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+const (
+	maxVolume = 100
+	maxLevel  = 200
+)
+
+// roleLabels are the names of the signal roles on screen.
+var roleLabels = map[string]string{
+	soundbank.ToLine:     "Zur Linie",
+	soundbank.Start:      "Start",
+	soundbank.End:        "Ende",
+	soundbank.Restart:    "Neustart",
+	soundbank.Final:      "Finale",
+	soundbank.Emergency:  "Notstopp",
+	soundbank.Timeout:    "Zeitablauf",
+	soundbank.Soundcheck: "Soundcheck",
+}
+
+// mixer holds the master volume in percent of the unchanged sounds and the
+// volume of each signal role in percent of the master volume. It is changed
+// by the game loop and the remote API; save writes the changes to the
+// configuration file.
+type mixer struct {
+	mu      sync.Mutex
+	master  int
+	levels  map[string]int
+	role    string
+	shown   time.Time
+	changed bool
+}
+
+var volumes = mixer{master: maxVolume, levels: map[string]int{}}
+
+func (m *mixer) Volume() remote.Volume {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := remote.Volume{Master: m.master, Signals: map[string]int{}}
+	for _, role := range soundbank.Roles {
+		v.Signals[role] = m.level(role)
+	}
+	return v
+}
+
+func (m *mixer) SetVolume(v remote.Volume) error {
+	if v.Master < 0 || v.Master > maxVolume {
+		return fmt.Errorf("master volume must be between 0 and %d", maxVolume)
+	}
+	for role, level := range v.Signals {
+		if _, ok := roleLabels[role]; !ok {
+			return fmt.Errorf("unknown signal %q", role)
+		}
+		if level < 0 || level > maxLevel {
+			return fmt.Errorf("signal volume must be between 0 and %d", maxLevel)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.master = v.Master
+	for role, level := range v.Signals {
+		m.levels[role] = level
+	}
+	m.apply()
+	m.show("")
+	return nil
+}
+
+// init sets the volumes loaded from the configuration file without
+// showing them.
+func (m *mixer) init(master int, levels map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.master = master
+	m.levels = map[string]int{}
+	for role, level := range levels {
+		m.levels[role] = level
+	}
+	m.apply()
+	m.changed = false
+}
+
+func (m *mixer) level(role string) int {
+	if level, ok := m.levels[role]; ok {
+		return level
+	}
+	return 100
+}
+
+// gain is the master volume as a factor for audio players.
+func (m *mixer) gain() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return float64(m.master) / 100
+}
+
+func (m *mixer) apply() {
+	soundBank.SetVolume(float64(m.master)/100, m.levels)
+	m.changed = true
+}
+
+func (m *mixer) show(role string) {
+	m.role = role
+	m.shown = time.Now()
+}
+
+func (m *mixer) visible() bool {
+	return time.Since(m.shown) < 2*time.Second
+}
+
+// update handles the volume keys: [V] selects the master volume or a
+// signal, [+] and [-] change it. The selection falls back to the master
+// volume when the overlay has disappeared.
+func (m *mixer) update() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.visible() {
+		m.role = ""
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		i := indexOf(soundbank.Roles, m.role)
+		if m.role == "" {
+			m.show(soundbank.Roles[0])
+		} else if i == len(soundbank.Roles)-1 {
+			m.show("")
+		} else {
+			m.show(soundbank.Roles[i+1])
+		}
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyEqual) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadAdd) {
+		m.change(1)
+	} else if inpututil.IsKeyJustPressed(ebiten.KeyMinus) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadSubtract) {
+		m.change(-1)
+	}
+}
+
+func (m *mixer) change(delta int) {
+	if m.role == "" {
+		m.master = clamp(m.master+delta*5, 0, maxVolume)
+	} else {
+		m.levels[m.role] = clamp(m.level(m.role)+delta*10, 0, maxLevel)
+	}
+	m.apply()
+	m.show(m.role)
+}
+
+// soundcheck plays the signal shown in the overlay or the test horn.
+func (m *mixer) soundcheck() {
+	m.mu.Lock()
+	role := m.role
+	if !m.visible() || role == "" {
+		role = soundbank.Soundcheck
+	}
+	m.mu.Unlock()
+	soundBank.Play(role)
+}
+
+// save writes changed volumes to the configuration file.
+func (m *mixer) save() {
+	m.mu.Lock()
+	if !m.changed {
+		m.mu.Unlock()
+		return
+	}
+	m.changed = false
+	settingsFile.Volume = m.master
+	settingsFile.Volumes = map[string]int{}
+	for role, level := range m.levels {
+		settingsFile.Volumes[role] = level
+	}
+	m.mu.Unlock()
+	if err := settingsFile.Save(configFile); err != nil {
+		log.Printf("saving volume: %v", err)
+	}
+}
+
+func (m *mixer) draw(screen *ebiten.Image) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.visible() {
+		return
+	}
+	label := "Lautstaerke"
+	value, max := m.master, maxVolume
+	if m.role != "" {
+		label += " " + roleLabels[m.role]
+		value, max = m.level(m.role), maxLevel
+	}
+	valueText := fmt.Sprintf("%d %%", value)
+	screen.SubImage(image.Rect(440, 60, 1000, 170)).(*ebiten.Image).Fill(colorDarkGray)
+	text.Draw(screen, label, infoFontLarge, 460, 100, colorWhite)
+	text.Draw(screen, valueText, infoFontLarge, 880, 100, colorYellow)
+	screen.SubImage(image.Rect(460, 120, 980, 150)).(*ebiten.Image).Fill(colorBlack)
+	screen.SubImage(image.Rect(460, 120, 460+520*value/max, 150)).(*ebiten.Image).Fill(colorGreen)
+}